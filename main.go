@@ -1,14 +1,19 @@
 package main
 
 import (
-	"bufio"
 	"bytes"
 	"encoding/json"
+	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"os"
+	"path/filepath"
 	"regexp"
 	"strings"
 
+	"github.com/junte/stable-diffusion-prompt-parser/src/comfyui"
+	"github.com/junte/stable-diffusion-prompt-parser/src/metadata"
 	"github.com/junte/stable-diffusion-prompt-parser/src/parser"
 )
 
@@ -16,74 +21,292 @@ type Output struct {
 	Evaluated  *parser.ParsedPrompt `json:"evaluated"`
 	Beautified string               `json:"beautified"`
 	Cleaned    string               `json:"cleaned"`
+	Negative   *Output              `json:"negative,omitempty"`
 }
 
-func toIndentedJson(output *Output, prefix string, indent string) ([]byte, error) {
-	buffer := &bytes.Buffer{}
-	encoder := json.NewEncoder(buffer)
-	encoder.SetEscapeHTML(false)
-	encoder.SetIndent(prefix, indent)
+func initializeSlices(parsed *parser.ParsedPrompt) {
+	if parsed.Tags == nil {
+		parsed.Tags = make([]*parser.PromptTag, 0)
+	}
+
+	if parsed.Hypernets == nil {
+		parsed.Hypernets = make([]*parser.PromptModel, 0)
+	}
 
-	// intialize slices to get rid of nulls in json
-	if output.Evaluated.Tags == nil {
-		output.Evaluated.Tags = make([]*parser.PromptTag, 0)
+	if parsed.Loras == nil {
+		parsed.Loras = make([]*parser.PromptModel, 0)
 	}
 
-	if output.Evaluated.Hypernets == nil {
-		output.Evaluated.Hypernets = make([]*parser.PromptModel, 0)
+	if parsed.Embeddings == nil {
+		parsed.Embeddings = make([]*parser.PromptEmbedding, 0)
 	}
 
-	if output.Evaluated.Loras == nil {
-		output.Evaluated.Loras = make([]*parser.PromptModel, 0)
+	if parsed.Networks == nil {
+		parsed.Networks = make([]*parser.PromptNetwork, 0)
 	}
 
+	if parsed.Wildcards == nil {
+		parsed.Wildcards = make([]*parser.PromptWildcard, 0)
+	}
+}
+
+func toIndentedJson(output any, prefix string, indent string) ([]byte, error) {
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.SetIndent(prefix, indent)
+
 	err := encoder.Encode(output)
 
 	return bytes.TrimRight(buffer.Bytes(), "\n"), err
 }
 
-func main() {
-	scanner := bufio.NewScanner(os.Stdin)
-
-	var input string
-	for scanner.Scan() {
-		line := scanner.Text()
-		if len(line) > 0 {
-			input += line + " "
-		}
-	}
-	if err := scanner.Err(); err != nil {
+func exitOnError(err error) {
+	if err != nil {
 		fmt.Fprintf(os.Stderr, "error: %v\n", err)
 		os.Exit(1)
 	}
+}
 
-	parser := parser.NewPromptParser()
+func readStdin() []byte {
+	data, err := io.ReadAll(os.Stdin)
+	exitOnError(err)
 
-	parsed, err := parser.ParsePrompt(input)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	return data
+}
+
+func readImageMetadata(data []byte) metadata.Metadata {
+	imageMetadata, err := metadata.Read(bytes.NewReader(data))
+	exitOnError(err)
+
+	return imageMetadata
+}
+
+// readInput returns the prompt text from stdin or the generation info of a piped image.
+func readInput() string {
+	return inputText(readStdin())
+}
+
+func inputText(data []byte) string {
+	if metadata.IsImage(data) {
+		parameters, found := readImageMetadata(data).Parameters()
+		if !found {
+			exitOnError(errors.New("image has no generation parameters"))
+		}
+
+		return parameters
 	}
 
-	beautified, err := parser.BeautifyPrompt(input)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+	lines := make([]string, 0)
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if len(line) > 0 {
+			lines = append(lines, line)
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// readParameters parses generation info, NovelAI images switch the parser to their dialect.
+func readParameters(promptParser *parser.PromptParser, data []byte) *parser.GenerationParameters {
+	if metadata.IsImage(data) {
+		if imageMetadata := readImageMetadata(data); imageMetadata.IsNovelAI() {
+			promptParser.SetDialect(parser.NovelAI)
+			parameters, err := promptParser.ParseNovelAIMetadata(imageMetadata)
+			exitOnError(err)
+
+			return parameters
+		}
 	}
 
+	parameters, err := promptParser.ParseInfotext(inputText(data))
+	exitOnError(err)
+
+	return parameters
+}
+
+// hasInfotextMarker checks for lines A1111 adds after the prompt
+func hasInfotextMarker(input string) bool {
+	return regexp.MustCompile(`(?m)^\s*(Negative prompt:|Steps: )`).MatchString(input)
+}
+
+func joinLines(input string) string {
+	return strings.ReplaceAll(input, "\n", " ")
+}
+
+func printJson(output any) {
+	marshalled, err := toIndentedJson(output, "", "  ")
+	exitOnError(err)
+
+	fmt.Fprintln(os.Stdout, string(marshalled))
+}
+
+func evaluate(promptParser *parser.PromptParser, input string) *Output {
+	parsed, err := promptParser.ParsePrompt(input)
+	exitOnError(err)
+	initializeSlices(parsed)
+
+	beautified, err := promptParser.BeautifyPrompt(input)
+	exitOnError(err)
+
 	regex := regexp.MustCompile(`,? ?<[^>]*>,? ?`)
 	cleaned := regex.ReplaceAllString(beautified, ", ")
-	output := Output{
+
+	return &Output{
 		Evaluated:  parsed,
 		Beautified: beautified,
 		Cleaned:    strings.Trim(cleaned, ", "),
 	}
+}
+
+// parse [--negative "prompt"] [--normalize] < prompt.txt
+func parse(args []string) {
+	flags := flag.NewFlagSet("parse", flag.ExitOnError)
+	negative := flags.String("negative", "", "negative prompt, overrides the \"Negative prompt:\" line of the input")
+	normalize := flags.Bool("normalize", false, "replace full-width punctuation, smart quotes and special spaces, changes are reported in diagnostics")
+	exitOnError(flags.Parse(args))
+
+	promptParser := parser.NewPromptParser()
+	promptParser.SetNormalization(*normalize)
+	// plain prompts are taken as is, "key: value" lines may be a part of them
+	data := readStdin()
+	var positive string
+	if !metadata.IsImage(data) {
+		positive = inputText(data)
+	}
 
-	marshalled, err := toIndentedJson(&output, "", "  ")
+	if metadata.IsImage(data) || hasInfotextMarker(positive) {
+		parameters := readParameters(promptParser, data)
+		positive = parameters.Prompt
+		if len(*negative) == 0 {
+			*negative = parameters.NegativePrompt
+		}
+	}
+
+	output := evaluate(promptParser, joinLines(positive))
+	if len(*negative) > 0 {
+		output.Negative = evaluate(promptParser, joinLines(*negative))
+	}
+
+	printJson(output)
+}
+
+// convert --from a1111 --to comfyui < prompt.txt
+func convert(args []string) {
+	flags := flag.NewFlagSet("convert", flag.ExitOnError)
+	from := flags.String("from", string(parser.A1111), "dialect of the input prompt (a1111, comfyui, novelai, compel, midjourney)")
+	to := flags.String("to", string(parser.A1111), "dialect of the output prompt (a1111, comfyui, novelai, compel, midjourney)")
+	exitOnError(flags.Parse(args))
+
+	converted, err := parser.NewPromptParser().Convert(joinLines(readInput()), parser.Dialect(*from), parser.Dialect(*to))
+	exitOnError(err)
+
+	printJson(converted)
+}
+
+// infotext < parameters.txt
+func infotext() {
+	parameters := readParameters(parser.NewPromptParser(), readStdin())
+	initializeSlices(parameters.Parsed.Positive)
+	initializeSlices(parameters.Parsed.Negative)
+
+	printJson(parameters)
+}
+
+// write -in image.png [-out result.png] [-strip] < parameters.txt
+func write(args []string) {
+	flags := flag.NewFlagSet("write", flag.ExitOnError)
+	in := flags.String("in", "", "PNG image to update")
+	out := flags.String("out", "", "result image, the input image is replaced by default")
+	strip := flags.Bool("strip", false, "remove other text chunks like ComfyUI workflow")
+	exitOnError(flags.Parse(args))
+
+	if len(*in) == 0 {
+		exitOnError(errors.New("-in is required"))
+	}
+	if len(*out) == 0 {
+		*out = *in
+	}
+
+	parameters := strings.TrimRight(string(readStdin()), "\r\n")
+
+	source, err := os.Open(*in)
+	exitOnError(err)
+	defer source.Close()
+
+	info, err := source.Stat()
+	exitOnError(err)
+
+	// write next to the result and rename, so the input can be replaced safely
+	target, err := os.CreateTemp(filepath.Dir(*out), ".prompt-*.png")
+	exitOnError(err)
+
+	err = metadata.WritePNG(source, target, metadata.Metadata{metadata.ParametersKey: parameters}, !*strip)
+	if err == nil {
+		err = target.Chmod(info.Mode().Perm())
+	}
+	if closeErr := target.Close(); err == nil {
+		err = closeErr
+	}
 	if err != nil {
-		fmt.Fprintf(os.Stderr, "error: %v\n", err)
-		os.Exit(1)
+		os.Remove(target.Name())
 	}
+	exitOnError(err)
 
-	fmt.Fprintln(os.Stdout, string(marshalled))
+	exitOnError(os.Rename(target.Name(), *out))
+}
+
+// metadata < image.png
+func printMetadata() {
+	printJson(readImageMetadata(readStdin()))
+}
+
+// comfyui < image.png or workflow.json
+func comfyUI() {
+	promptParser := parser.NewPromptParser()
+	promptParser.SetDialect(parser.ComfyUI)
+	extractor := comfyui.NewExtractor(promptParser)
+
+	data := readStdin()
+
+	var prompts []*comfyui.SamplerPrompt
+	var err error
+	if metadata.IsImage(data) {
+		prompts, err = extractor.ExtractMetadata(readImageMetadata(data))
+	} else {
+		prompts, err = extractor.Extract(data)
+	}
+	exitOnError(err)
+
+	for _, prompt := range prompts {
+		initializeSlices(prompt.Parsed.Positive)
+		initializeSlices(prompt.Parsed.Negative)
+	}
+
+	printJson(prompts)
+}
+
+func main() {
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "convert":
+			convert(os.Args[2:])
+			return
+		case "comfyui":
+			comfyUI()
+			return
+		case "write":
+			write(os.Args[2:])
+			return
+		case "metadata":
+			printMetadata()
+			return
+		case "infotext":
+			infotext()
+			return
+		}
+	}
+
+	parse(os.Args[1:])
 }
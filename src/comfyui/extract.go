@@ -0,0 +1,315 @@
+package comfyui
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/junte/stable-diffusion-prompt-parser/src/metadata"
+	"github.com/junte/stable-diffusion-prompt-parser/src/parser"
+)
+
+// Inputs holding prompt text of CLIPTextEncode and its SDXL, refiner and Flux variants
+var textInputs = []string{"text", "text_g", "text_l", "clip_l", "t5xxl"}
+
+// Inputs holding the value of primitive and string nodes
+var valueInputs = []string{"value", "string", "text", "prompt"}
+
+var modelExtensions = []string{".safetensors", ".ckpt", ".pt", ".bin"}
+
+type SamplerPrompt struct {
+	Node           string                   `json:"node"`
+	Class          string                   `json:"class"`
+	Prompt         string                   `json:"prompt"`
+	NegativePrompt string                   `json:"negativePrompt"`
+	Parsed         *parser.ParsedPromptPair `json:"parsed"`
+}
+
+type Extractor struct {
+	parser *parser.PromptParser
+}
+
+// NewExtractor parses found prompts with the given parser, usually set to the ComfyUI dialect.
+func NewExtractor(promptParser *parser.PromptParser) *Extractor {
+	return &Extractor{parser: promptParser}
+}
+
+// ExtractMetadata uses the API graph from the "prompt" chunk or the UI graph from the "workflow" chunk.
+func (extractor *Extractor) ExtractMetadata(imageMetadata metadata.Metadata) ([]*SamplerPrompt, error) {
+	for _, key := range []string{"prompt", "workflow"} {
+		if data, found := imageMetadata[key]; found {
+			return extractor.Extract([]byte(data))
+		}
+	}
+
+	return []*SamplerPrompt{}, errors.New("no ComfyUI graph found")
+}
+
+// Extract returns prompts of every sampler in API or UI workflow JSON.
+func (extractor *Extractor) Extract(data []byte) ([]*SamplerPrompt, error) {
+	var root map[string]json.RawMessage
+	if err := json.Unmarshal(data, &root); err != nil {
+		return []*SamplerPrompt{}, fmt.Errorf("invalid ComfyUI graph: %w", err)
+	}
+
+	var graph graph
+	var err error
+	if _, found := root["nodes"]; found {
+		graph, err = parseWorkflow(data)
+	} else {
+		graph, err = parseAPI(data)
+	}
+	if err != nil {
+		return []*SamplerPrompt{}, fmt.Errorf("invalid ComfyUI graph: %w", err)
+	}
+
+	prompts := make([]*SamplerPrompt, 0)
+	for _, id := range graph.sortedIDs() {
+		node := graph[id]
+		if !isSampler(node) {
+			continue
+		}
+
+		prompt, err := extractor.extractSampler(graph, id, node)
+		if err != nil {
+			return []*SamplerPrompt{}, err
+		}
+
+		prompts = append(prompts, prompt)
+	}
+
+	return prompts, nil
+}
+
+func (extractor *Extractor) extractSampler(graph graph, id string, sampler *node) (*SamplerPrompt, error) {
+	positive, negative, model := graph.samplerInputs(sampler)
+
+	positiveTexts, positiveClips := graph.conditioningTexts(positive, "positive", map[string]bool{})
+	negativeTexts, negativeClips := graph.conditioningTexts(negative, "negative", map[string]bool{})
+
+	prompt := &SamplerPrompt{
+		Node:           id,
+		Class:          sampler.Class,
+		Prompt:         strings.Join(positiveTexts, ", "),
+		NegativePrompt: strings.Join(negativeTexts, ", "),
+	}
+
+	parsed, err := extractor.parser.ParsePromptPair(prompt.Prompt, prompt.NegativePrompt)
+	if err != nil {
+		return prompt, err
+	}
+
+	visited := map[string]bool{}
+	loras := make([]*parser.PromptModel, 0)
+	for _, source := range append(append([]any{model}, positiveClips...), negativeClips...) {
+		loras = append(loras, graph.loraChain(source, visited)...)
+	}
+	parsed.Positive.Loras = append(parsed.Positive.Loras, loras...)
+	prompt.Parsed = parsed
+
+	return prompt, nil
+}
+
+func isSampler(node *node) bool {
+	return strings.Contains(node.Class, "KSampler") || strings.HasPrefix(node.Class, "SamplerCustom")
+}
+
+// samplerInputs follows the guider of custom samplers to find conditioning and model inputs.
+func (graph graph) samplerInputs(sampler *node) (any, any, any) {
+	inputs := sampler.Inputs
+	if guider, ok := asLink(inputs["guider"]); ok && graph[guider.node] != nil {
+		inputs = graph[guider.node].Inputs
+	}
+
+	positive, found := inputs["positive"]
+	if !found {
+		positive = inputs["conditioning"]
+	}
+
+	return positive, inputs["negative"], inputs["model"]
+}
+
+// conditioningTexts walks conditioning links up to text encoders, it returns
+// their texts and clip inputs. side picks the output of nodes like ControlNetApplyAdvanced.
+func (graph graph) conditioningTexts(value any, side string, visited map[string]bool) ([]string, []any) {
+	source, ok := asLink(value)
+	if !ok || visited[source.node] || graph[source.node] == nil {
+		return []string{}, []any{}
+	}
+	visited[source.node] = true
+
+	node := graph[source.node]
+	texts := make([]string, 0)
+	for _, name := range textInputs {
+		if text, found := graph.resolveText(node.Inputs[name], map[string]bool{}); found && !slices.Contains(texts, text) {
+			texts = append(texts, text)
+		}
+	}
+
+	if len(texts) > 0 {
+		return texts, []any{node.Inputs["clip"]}
+	}
+
+	clips := make([]any, 0)
+	for _, next := range graph.conditioningSources(node, source.slot, side) {
+		nextTexts, nextClips := graph.conditioningTexts(next, side, visited)
+		texts = append(texts, nextTexts...)
+		clips = append(clips, nextClips...)
+	}
+
+	return texts, clips
+}
+
+func (graph graph) conditioningSources(node *node, slot int, side string) []any {
+	_, hasPositive := node.Inputs["positive"]
+	_, hasNegative := node.Inputs["negative"]
+	if hasPositive && hasNegative {
+		// outputs are positive, negative
+		if slot == 1 {
+			return []any{node.Inputs["negative"]}
+		}
+		return []any{node.Inputs["positive"]}
+	}
+
+	sources := make([]any, 0)
+	links := make([]any, 0)
+	for _, name := range sortedKeys(node.Inputs) {
+		if _, ok := asLink(node.Inputs[name]); !ok {
+			continue
+		}
+
+		links = append(links, node.Inputs[name])
+		if strings.Contains(name, "conditioning") || name == side {
+			sources = append(sources, node.Inputs[name])
+		}
+	}
+
+	// reroute and other pass-through nodes
+	if len(sources) == 0 && len(links) == 1 {
+		return links
+	}
+
+	return sources
+}
+
+// resolveText returns a string input or the value of a linked primitive node.
+func (graph graph) resolveText(value any, visited map[string]bool) (string, bool) {
+	if text, ok := value.(string); ok {
+		return text, len(strings.TrimSpace(text)) > 0
+	}
+
+	source, ok := asLink(value)
+	if !ok || visited[source.node] || graph[source.node] == nil {
+		return "", false
+	}
+	visited[source.node] = true
+
+	for _, name := range valueInputs {
+		if text, found := graph.resolveText(graph[source.node].Inputs[name], visited); found {
+			return text, true
+		}
+	}
+
+	return "", false
+}
+
+func (graph graph) resolveNumber(value any, visited map[string]bool) (float64, bool) {
+	if number, ok := value.(float64); ok {
+		return number, true
+	}
+
+	source, ok := asLink(value)
+	if !ok || visited[source.node] || graph[source.node] == nil {
+		return 0, false
+	}
+	visited[source.node] = true
+
+	return graph.resolveNumber(graph[source.node].Inputs["value"], visited)
+}
+
+// loraChain collects LoRA loaders up the model and clip links, nearest to the checkpoint first.
+func (graph graph) loraChain(value any, visited map[string]bool) []*parser.PromptModel {
+	source, ok := asLink(value)
+	if !ok || visited[source.node] || graph[source.node] == nil {
+		return []*parser.PromptModel{}
+	}
+	visited[source.node] = true
+
+	node := graph[source.node]
+	loras := graph.loraChain(node.Inputs["model"], visited)
+	loras = append(loras, graph.loraChain(node.Inputs["clip"], visited)...)
+
+	if lora := graph.lora(node); lora != nil {
+		loras = append(loras, lora)
+	}
+
+	return loras
+}
+
+func (graph graph) lora(node *node) *parser.PromptModel {
+	name, found := graph.resolveText(node.Inputs["lora_name"], map[string]bool{})
+	if !found || name == "None" {
+		return nil
+	}
+
+	unet, hasUNet := graph.resolveNumber(node.Inputs["strength_model"], map[string]bool{})
+	textEncoder, hasTextEncoder := graph.resolveNumber(node.Inputs["strength_clip"], map[string]bool{})
+	if !hasUNet {
+		unet = 1
+	}
+	if !hasTextEncoder {
+		// model only loaders
+		if _, hasClip := node.Inputs["clip"]; hasClip {
+			textEncoder = 1
+		}
+	}
+
+	lora := &parser.PromptModel{Filename: modelName(name), Multiplier: textEncoder}
+	if textEncoder != unet {
+		lora.TextEncoder = &textEncoder
+		lora.UNet = &unet
+	}
+
+	return lora
+}
+
+// modelName removes the extension, same as A1111 lora names.
+func modelName(filename string) string {
+	extension := path.Ext(filename)
+	if slices.Contains(modelExtensions, strings.ToLower(extension)) {
+		return strings.TrimSuffix(filename, extension)
+	}
+
+	return filename
+}
+
+// sortedIDs orders numeric ids by value.
+func (graph graph) sortedIDs() []string {
+	ids := make([]string, 0, len(graph))
+	for id := range graph {
+		ids = append(ids, id)
+	}
+
+	sort.Slice(ids, func(i, j int) bool {
+		if len(ids[i]) != len(ids[j]) {
+			return len(ids[i]) < len(ids[j])
+		}
+		return ids[i] < ids[j]
+	})
+
+	return ids
+}
+
+func sortedKeys(values map[string]any) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
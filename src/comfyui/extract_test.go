@@ -0,0 +1,202 @@
+package comfyui
+
+import (
+	"testing"
+
+	"github.com/junte/stable-diffusion-prompt-parser/src/metadata"
+	"github.com/junte/stable-diffusion-prompt-parser/src/parser"
+	"github.com/stretchr/testify/assert"
+)
+
+func newExtractor() *Extractor {
+	promptParser := parser.NewPromptParser()
+	promptParser.SetDialect(parser.ComfyUI)
+
+	return NewExtractor(promptParser)
+}
+
+func float(value float64) *float64 {
+	return &value
+}
+
+const apiGraph = `{
+	"4": {"class_type": "CheckpointLoaderSimple", "inputs": {"ckpt_name": "sdxl.safetensors"}},
+	"10": {"class_type": "LoraLoader", "inputs": {"lora_name": "style.safetensors", "strength_model": 0.8, "strength_clip": 0.8, "model": ["4", 0], "clip": ["4", 1]}},
+	"11": {"class_type": "LoraLoader", "inputs": {"lora_name": "sdxl/detail.pt", "strength_model": 1, "strength_clip": 0.5, "model": ["10", 0], "clip": ["10", 1]}},
+	"12": {"class_type": "LoraLoaderModelOnly", "inputs": {"lora_name": "turbo.safetensors", "strength_model": ["13", 0], "model": ["11", 0]}},
+	"13": {"class_type": "PrimitiveFloat", "inputs": {"value": 0.3}},
+	"6": {"class_type": "CLIPTextEncodeSDXL", "inputs": {"text_g": "(cat:1.2), garden", "text_l": "(cat:1.2), garden", "width": 1024, "clip": ["11", 1]}},
+	"7": {"class_type": "CLIPTextEncode", "inputs": {"text": ["14", 0], "clip": ["11", 1]}},
+	"14": {"class_type": "PrimitiveString", "inputs": {"value": "blurry, embedding:bad"}},
+	"8": {"class_type": "CLIPTextEncode", "inputs": {"text": "sunset", "clip": ["11", 1]}},
+	"9": {"class_type": "ConditioningCombine", "inputs": {"conditioning_1": ["6", 0], "conditioning_2": ["8", 0]}},
+	"15": {"class_type": "ControlNetApplyAdvanced", "inputs": {"positive": ["9", 0], "negative": ["7", 0], "strength": 1}},
+	"3": {"class_type": "KSampler", "inputs": {"seed": 1, "steps": 20, "model": ["12", 0], "positive": ["15", 0], "negative": ["15", 1]}}
+}`
+
+func TestExtractAPI(t *testing.T) {
+	prompts, err := newExtractor().Extract([]byte(apiGraph))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []*SamplerPrompt{
+		{
+			Node:           "3",
+			Class:          "KSampler",
+			Prompt:         "(cat:1.2), garden, sunset",
+			NegativePrompt: "blurry, embedding:bad",
+			Parsed: &parser.ParsedPromptPair{
+				Positive: &parser.ParsedPrompt{
+					Tags: []*parser.PromptTag{
+						{Tag: "cat", Weight: 1.2},
+						{Tag: "garden", Weight: 1},
+						{Tag: "sunset", Weight: 1},
+					},
+					Loras: []*parser.PromptModel{
+						{Filename: "style", Multiplier: 0.8},
+						{Filename: "sdxl/detail", Multiplier: 0.5, TextEncoder: float(0.5), UNet: float(1)},
+						{Filename: "turbo", Multiplier: 0, TextEncoder: float(0), UNet: float(0.3)},
+					},
+				},
+				Negative: &parser.ParsedPrompt{
+					Tags:       []*parser.PromptTag{{Tag: "blurry", Weight: 1}},
+					Embeddings: []*parser.PromptEmbedding{{Name: "bad", Weight: 1}},
+				},
+			},
+		},
+	}, prompts)
+}
+
+func TestExtractCustomSampler(t *testing.T) {
+	graph := `{
+		"1": {"class_type": "CLIPTextEncode", "inputs": {"text": "cat", "clip": ["5", 1]}},
+		"2": {"class_type": "BasicGuider", "inputs": {"conditioning": ["1", 0], "model": ["5", 0]}},
+		"3": {"class_type": "SamplerCustomAdvanced", "inputs": {"guider": ["2", 0]}},
+		"5": {"class_type": "LoraLoader", "inputs": {"lora_name": "flux.safetensors", "strength_model": 1, "strength_clip": 1, "model": ["6", 0], "clip": ["6", 1]}},
+		"6": {"class_type": "CheckpointLoaderSimple", "inputs": {}}
+	}`
+
+	prompts, err := newExtractor().Extract([]byte(graph))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(prompts))
+	assert.Equal(t, "cat", prompts[0].Prompt)
+	assert.Equal(t, "", prompts[0].NegativePrompt)
+	assert.Equal(t, []*parser.PromptModel{{Filename: "flux", Multiplier: 1}}, prompts[0].Parsed.Positive.Loras)
+}
+
+const workflowGraph = `{
+	"nodes": [
+		{"id": 4, "type": "CheckpointLoaderSimple", "mode": 0, "widgets_values": ["sd15.safetensors"]},
+		{"id": 10, "type": "LoraLoader", "mode": 0, "widgets_values": ["style.safetensors", 0.7, 0.7],
+			"inputs": [{"name": "model", "link": 1}, {"name": "clip", "link": 2}]},
+		{"id": 6, "type": "CLIPTextEncode", "mode": 0, "widgets_values": ["cat, <lora:extra:0.5>"],
+			"inputs": [{"name": "clip", "link": 3}]},
+		{"id": 7, "type": "CLIPTextEncode", "mode": 0, "widgets_values": ["ignored"],
+			"inputs": [{"name": "clip", "link": 4}, {"name": "text", "link": 9}]},
+		{"id": 12, "type": "PrimitiveNode", "mode": 0, "widgets_values": ["lowres"]},
+		{"id": 13, "type": "Reroute", "mode": 0, "inputs": [{"name": "", "link": 5}]},
+		{"id": 3, "type": "KSampler", "mode": 0, "widgets_values": [1, "fixed", 20, 7, "euler", "normal", 1],
+			"inputs": [{"name": "model", "link": 6}, {"name": "positive", "link": 7}, {"name": "negative", "link": 8}]},
+		{"id": 20, "type": "KSampler", "mode": 2, "widgets_values": [],
+			"inputs": [{"name": "positive", "link": 7}]}
+	],
+	"links": [
+		[1, 4, 0, 10, 0, "MODEL"],
+		[2, 4, 1, 10, 1, "CLIP"],
+		[3, 10, 1, 6, 0, "CLIP"],
+		[4, 10, 1, 7, 0, "CLIP"],
+		[5, 6, 0, 13, 0, "*"],
+		[6, 10, 0, 3, 0, "MODEL"],
+		[7, 13, 0, 3, 1, "CONDITIONING"],
+		[8, 7, 0, 3, 2, "CONDITIONING"],
+		[9, 12, 0, 7, 1, "STRING"]
+	]
+}`
+
+func TestExtractWorkflow(t *testing.T) {
+	prompts, err := newExtractor().Extract([]byte(workflowGraph))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []*SamplerPrompt{
+		{
+			Node:           "3",
+			Class:          "KSampler",
+			Prompt:         "cat, <lora:extra:0.5>",
+			NegativePrompt: "lowres",
+			Parsed: &parser.ParsedPromptPair{
+				Positive: &parser.ParsedPrompt{
+					Tags: []*parser.PromptTag{{Tag: "cat", Weight: 1}},
+					Loras: []*parser.PromptModel{
+						{Filename: "extra", Multiplier: 0.5},
+						{Filename: "style", Multiplier: 0.7},
+					},
+				},
+				Negative: &parser.ParsedPrompt{
+					Tags: []*parser.PromptTag{{Tag: "lowres", Weight: 1}},
+				},
+			},
+		},
+	}, prompts)
+}
+
+func TestExtractBypassedWorkflow(t *testing.T) {
+	data := `{
+		"nodes": [
+			{"id": 4, "type": "CheckpointLoaderSimple", "mode": 0, "widgets_values": ["sd15.safetensors"]},
+			{"id": 10, "type": "LoraLoader", "mode": 4, "widgets_values": ["style.safetensors", 0.7, 0.7],
+				"inputs": [{"name": "model", "type": "MODEL", "link": 1}, {"name": "clip", "type": "CLIP", "link": 2}],
+				"outputs": [{"type": "MODEL"}, {"type": "CLIP"}]},
+			{"id": 11, "type": "LoraLoader", "mode": 0, "widgets_values": ["detail.safetensors", 0.5, 0.5],
+				"inputs": [{"name": "model", "type": "MODEL", "link": 3}, {"name": "clip", "type": "CLIP", "link": 4}],
+				"outputs": [{"type": "MODEL"}, {"type": "CLIP"}]},
+			{"id": 6, "type": "CLIPTextEncode", "mode": 0, "widgets_values": ["cat"],
+				"inputs": [{"name": "clip", "type": "CLIP", "link": 5}]},
+			{"id": 3, "type": "KSampler", "mode": 0, "widgets_values": [1, "fixed", 20, 7, "euler", "normal", 1],
+				"inputs": [{"name": "model", "type": "MODEL", "link": 6}, {"name": "positive", "type": "CONDITIONING", "link": 7}]}
+		],
+		"links": [
+			[1, 4, 0, 10, 0, "MODEL"],
+			[2, 4, 1, 10, 1, "CLIP"],
+			[3, 10, 0, 11, 0, "MODEL"],
+			[4, 10, 1, 11, 1, "CLIP"],
+			[5, 11, 1, 6, 0, "CLIP"],
+			[6, 11, 0, 3, 0, "MODEL"],
+			[7, 6, 0, 3, 1, "CONDITIONING"]
+		]
+	}`
+
+	prompts, err := newExtractor().Extract([]byte(data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, 1, len(prompts))
+	assert.Equal(t, []*parser.PromptModel{{Filename: "detail", Multiplier: 0.5}}, prompts[0].Parsed.Positive.Loras)
+}
+
+func TestExtractMetadata(t *testing.T) {
+	extractor := newExtractor()
+
+	prompts, err := extractor.ExtractMetadata(metadata.Metadata{"prompt": apiGraph, "workflow": "{"})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "3", prompts[0].Node)
+
+	prompts, err = extractor.ExtractMetadata(metadata.Metadata{"workflow": workflowGraph})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "3", prompts[0].Node)
+
+	_, err = extractor.ExtractMetadata(metadata.Metadata{"parameters": "cat"})
+	assert.EqualError(t, err, "no ComfyUI graph found")
+}
+
+func TestExtractErrors(t *testing.T) {
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"[1, 2]", "invalid ComfyUI graph: json: cannot unmarshal array"},
+		{`{"nodes": [], "links": [["a", 1, 0]]}`, "invalid ComfyUI graph: invalid link [a 1 0]"},
+		{`{"3": {"class_type": 1}}`, "invalid ComfyUI graph: json: cannot unmarshal number"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := newExtractor().Extract([]byte(test.input))
+			assert.ErrorContains(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,221 @@
+package comfyui
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+)
+
+// node of the API format graph, links are stored as ["id", slot] inputs
+type node struct {
+	Class  string         `json:"class_type"`
+	Inputs map[string]any `json:"inputs"`
+}
+
+type graph map[string]*node
+
+type link struct {
+	node string
+	slot int
+}
+
+type workflow struct {
+	Nodes []*workflowNode `json:"nodes"`
+	Links [][]any         `json:"links"`
+}
+
+type workflowNode struct {
+	ID      any    `json:"id"`
+	Type    string `json:"type"`
+	Mode    int    `json:"mode"`
+	Widgets any    `json:"widgets_values"`
+	Inputs  []struct {
+		Name string `json:"name"`
+		Type string `json:"type"`
+		Link *int   `json:"link"`
+	} `json:"inputs"`
+	Outputs []struct {
+		Type string `json:"type"`
+	} `json:"outputs"`
+}
+
+// Muted nodes are kept out of the graph, bypassed nodes pass their inputs through
+// to the outputs of the same type
+const (
+	mutedMode    = 2
+	bypassedMode = 4
+)
+
+// Widget values of UI workflows are positional, names follow node definitions.
+var widgetNames = map[string][]string{
+	"CLIPTextEncode":            {"text"},
+	"CLIPTextEncodeSDXL":        {"width", "height", "crop_w", "crop_h", "target_width", "target_height", "text_g", "text_l"},
+	"CLIPTextEncodeSDXLRefiner": {"ascore", "width", "height", "text"},
+	"CLIPTextEncodeFlux":        {"clip_l", "t5xxl", "guidance"},
+	"LoraLoader":                {"lora_name", "strength_model", "strength_clip"},
+	"LoraLoaderModelOnly":       {"lora_name", "strength_model"},
+	"KSampler":                  {"seed", "control_after_generate", "steps", "cfg", "sampler_name", "scheduler", "denoise"},
+	"KSamplerAdvanced":          {"add_noise", "noise_seed", "control_after_generate", "steps", "cfg", "sampler_name", "scheduler", "start_at_step", "end_at_step", "return_with_leftover_noise"},
+	"PrimitiveNode":             {"value"},
+}
+
+func parseAPI(data []byte) (graph, error) {
+	result := make(graph)
+	err := json.Unmarshal(data, &result)
+
+	for id, node := range result {
+		if node == nil || node.Inputs == nil {
+			delete(result, id)
+		}
+	}
+
+	return result, err
+}
+
+// parseWorkflow converts UI workflow into the API format graph.
+func parseWorkflow(data []byte) (graph, error) {
+	var parsed workflow
+	if err := json.Unmarshal(data, &parsed); err != nil {
+		return nil, err
+	}
+
+	links := make(map[int]link)
+	for _, item := range parsed.Links {
+		// [id, from node, from slot, to node, to slot, type]
+		if len(item) < 3 {
+			return nil, fmt.Errorf("invalid link %v", item)
+		}
+
+		id, ok := item[0].(float64)
+		slot, slotOk := item[2].(float64)
+		if !ok || !slotOk {
+			return nil, fmt.Errorf("invalid link %v", item)
+		}
+
+		links[int(id)] = link{node: nodeID(item[1]), slot: int(slot)}
+	}
+
+	bypassed := make(map[string]map[int]link)
+	for _, workflowNode := range parsed.Nodes {
+		if workflowNode.Mode == bypassedMode {
+			bypassed[nodeID(workflowNode.ID)] = workflowNode.passThrough(links)
+		}
+	}
+
+	// links from bypassed nodes lead to their inputs, possibly through other bypassed nodes
+	resolve := func(target link) (link, bool) {
+		for i := 0; i <= len(bypassed); i++ {
+			passThrough, found := bypassed[target.node]
+			if !found {
+				return target, true
+			}
+
+			if target, found = passThrough[target.slot]; !found {
+				return link{}, false
+			}
+		}
+
+		return link{}, false
+	}
+
+	result := make(graph)
+	for _, workflowNode := range parsed.Nodes {
+		if workflowNode.Mode == mutedMode || workflowNode.Mode == bypassedMode {
+			continue
+		}
+
+		node := &node{Class: workflowNode.Type, Inputs: make(map[string]any)}
+
+		switch widgets := workflowNode.Widgets.(type) {
+		case []any:
+			for i, name := range widgetNames[workflowNode.Type] {
+				if i < len(widgets) {
+					node.Inputs[name] = widgets[i]
+				}
+			}
+		case map[string]any:
+			for name, value := range widgets {
+				node.Inputs[name] = value
+			}
+		}
+
+		for _, input := range workflowNode.Inputs {
+			if input.Link == nil {
+				continue
+			}
+
+			if target, found := links[*input.Link]; found {
+				if target, found = resolve(target); found {
+					node.Inputs[input.Name] = []any{target.node, float64(target.slot)}
+				}
+			}
+		}
+
+		result[nodeID(workflowNode.ID)] = node
+	}
+
+	return result, nil
+}
+
+// passThrough maps output slots of a bypassed node to the links of its inputs, an input
+// in the same slot is preferred, otherwise the first one of the output type is used
+func (workflowNode *workflowNode) passThrough(links map[int]link) map[int]link {
+	result := make(map[int]link)
+	for slot := 0; slot < max(len(workflowNode.Outputs), len(workflowNode.Inputs)); slot++ {
+		outputType := ""
+		if slot < len(workflowNode.Outputs) {
+			outputType = workflowNode.Outputs[slot].Type
+		}
+
+		candidates := []int{slot}
+		for i, input := range workflowNode.Inputs {
+			if outputType != "" && input.Type == outputType {
+				candidates = append(candidates, i)
+			}
+		}
+
+		for _, i := range candidates {
+			if i >= len(workflowNode.Inputs) || workflowNode.Inputs[i].Link == nil {
+				continue
+			}
+
+			input := workflowNode.Inputs[i]
+			if outputType != "" && input.Type != "" && input.Type != outputType {
+				continue
+			}
+
+			if target, found := links[*input.Link]; found {
+				result[slot] = target
+				break
+			}
+		}
+	}
+
+	return result
+}
+
+func nodeID(value any) string {
+	switch value := value.(type) {
+	case float64:
+		return strconv.FormatFloat(value, 'f', -1, 64)
+	case string:
+		return value
+	default:
+		return fmt.Sprint(value)
+	}
+}
+
+func asLink(value any) (link, bool) {
+	items, ok := value.([]any)
+	if !ok || len(items) != 2 {
+		return link{}, false
+	}
+
+	id, ok := items[0].(string)
+	slot, slotOk := items[1].(float64)
+	if !ok || !slotOk {
+		return link{}, false
+	}
+
+	return link{node: id, slot: int(slot)}, true
+}
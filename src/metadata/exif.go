@@ -0,0 +1,153 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"strings"
+	"unicode/utf16"
+)
+
+const (
+	exifIFDPointerTag = 0x8769
+	userCommentTag    = 0x9286
+	userCommentPrefix = 8
+)
+
+var (
+	exifHeader       = []byte("Exif\x00\x00")
+	errInvalidExif   = errors.New("invalid EXIF data")
+	unicodeCharset   = []byte("UNICODE\x00")
+	asciiCharset     = []byte("ASCII\x00\x00\x00")
+	undefinedCharset = []byte("\x00\x00\x00\x00\x00\x00\x00\x00")
+)
+
+type tiffReader struct {
+	data  []byte
+	order binary.ByteOrder
+}
+
+// readExifUserComment finds UserComment in the Exif IFD of TIFF structured data.
+func readExifUserComment(data []byte) (string, bool, error) {
+	data = bytes.TrimPrefix(data, exifHeader)
+	if len(data) < 8 {
+		return "", false, errInvalidExif
+	}
+
+	tiff := &tiffReader{data: data}
+	switch string(data[:2]) {
+	case "II":
+		tiff.order = binary.LittleEndian
+	case "MM":
+		tiff.order = binary.BigEndian
+	default:
+		return "", false, errInvalidExif
+	}
+
+	if tiff.order.Uint16(data[2:]) != 42 {
+		return "", false, errInvalidExif
+	}
+
+	pointer, found, err := tiff.findEntry(tiff.order.Uint32(data[4:]), exifIFDPointerTag)
+	if err != nil || !found {
+		return "", false, err
+	}
+
+	// the pointer is a single LONG offset
+	if len(pointer) != 4 {
+		return "", false, errInvalidExif
+	}
+
+	comment, found, err := tiff.findEntry(tiff.order.Uint32(pointer), userCommentTag)
+	if err != nil || !found {
+		return "", false, err
+	}
+
+	return decodeUserComment(comment, tiff.order), true, nil
+}
+
+// findEntry returns the value bytes of the tag in the IFD at offset.
+func (tiff *tiffReader) findEntry(offset uint32, tag uint16) ([]byte, bool, error) {
+	if uint64(offset)+2 > uint64(len(tiff.data)) {
+		return nil, false, errInvalidExif
+	}
+
+	count := int(tiff.order.Uint16(tiff.data[offset:]))
+	entries := tiff.data[offset+2:]
+	if len(entries) < count*12 {
+		return nil, false, errInvalidExif
+	}
+
+	for i := 0; i < count; i++ {
+		entry := entries[i*12 : i*12+12]
+		if tiff.order.Uint16(entry) != tag {
+			continue
+		}
+
+		size := uint64(typeSize(tiff.order.Uint16(entry[2:]))) * uint64(tiff.order.Uint32(entry[4:]))
+		if size <= 4 {
+			return entry[8 : 8+size], true, nil
+		}
+
+		start := uint64(tiff.order.Uint32(entry[8:]))
+		if start+size > uint64(len(tiff.data)) {
+			return nil, false, errInvalidExif
+		}
+
+		return tiff.data[start : start+size], true, nil
+	}
+
+	return nil, false, nil
+}
+
+func typeSize(kind uint16) uint32 {
+	switch kind {
+	case 3, 8: // SHORT, SSHORT
+		return 2
+	case 4, 9, 11: // LONG, SLONG, FLOAT
+		return 4
+	case 5, 10, 12: // RATIONAL, SRATIONAL, DOUBLE
+		return 8
+	default: // BYTE, ASCII, UNDEFINED, ...
+		return 1
+	}
+}
+
+// decodeUserComment decodes the value by its 8 byte charset prefix.
+func decodeUserComment(data []byte, order binary.ByteOrder) string {
+	if len(data) < userCommentPrefix {
+		return strings.TrimRight(string(data), "\x00")
+	}
+
+	charset, text := data[:userCommentPrefix], data[userCommentPrefix:]
+	switch {
+	case bytes.Equal(charset, unicodeCharset):
+		return strings.TrimRight(decodeUTF16(text, order), "\x00")
+	case bytes.Equal(charset, asciiCharset), bytes.Equal(charset, undefinedCharset):
+		return strings.TrimRight(string(text), "\x00")
+	default:
+		return strings.TrimRight(string(data), "\x00")
+	}
+}
+
+// decodeUTF16 uses BOM or the position of zero bytes in ASCII characters
+// to pick byte order, tools don't agree on following the TIFF byte order.
+func decodeUTF16(data []byte, order binary.ByteOrder) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xfe, 0xff}):
+		order, data = binary.BigEndian, data[2:]
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		order, data = binary.LittleEndian, data[2:]
+	case len(data) >= 2 && data[0] == 0 && data[1] != 0:
+		order = binary.BigEndian
+	case len(data) >= 2 && data[0] != 0 && data[1] == 0:
+		order = binary.LittleEndian
+	}
+
+	units := make([]uint16, len(data)/2)
+	for i := range units {
+		units[i] = order.Uint16(data[i*2:])
+	}
+
+	return string(utf16.Decode(units))
+}
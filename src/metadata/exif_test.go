@@ -0,0 +1,112 @@
+package metadata
+
+import (
+	"encoding/binary"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type byteOrder interface {
+	binary.ByteOrder
+	binary.AppendByteOrder
+}
+
+// makeExif builds "Exif" APP1 data with IFD0 pointing to an Exif IFD holding the UserComment.
+func makeExif(order byteOrder, comment []byte) []byte {
+	data := []byte("Exif\x00\x00")
+	if order == binary.LittleEndian {
+		data = append(data, "II"...)
+	} else {
+		data = append(data, "MM"...)
+	}
+
+	tiff := order.AppendUint16(data[len(exifHeader):], 42)
+	tiff = order.AppendUint32(tiff, 8)
+
+	// IFD0 at 8, Exif IFD at 26, comment at 44
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, exifIFDPointerTag)
+	tiff = order.AppendUint16(tiff, 4)
+	tiff = order.AppendUint32(tiff, 1)
+	tiff = order.AppendUint32(tiff, 26)
+	tiff = order.AppendUint32(tiff, 0)
+
+	tiff = order.AppendUint16(tiff, 1)
+	tiff = order.AppendUint16(tiff, userCommentTag)
+	tiff = order.AppendUint16(tiff, 7)
+	tiff = order.AppendUint32(tiff, uint32(len(comment)))
+	tiff = order.AppendUint32(tiff, 44)
+	tiff = order.AppendUint32(tiff, 0)
+
+	return append(append(data[:len(exifHeader)], tiff...), comment...)
+}
+
+func encodeUTF16(order byteOrder, text string) []byte {
+	encoded := []byte{}
+	for _, unit := range utf16.Encode([]rune(text)) {
+		encoded = order.AppendUint16(encoded, unit)
+	}
+
+	return encoded
+}
+
+func TestReadExifUserComment(t *testing.T) {
+	text := "cat, (dog:1.2), кот\nSteps: 20"
+
+	tests := []struct {
+		name    string
+		data    []byte
+		comment string
+		found   bool
+		err     string
+	}{
+		{"unicode big endian", makeExif(binary.BigEndian, append([]byte("UNICODE\x00"), encodeUTF16(binary.BigEndian, text)...)), text, true, ""},
+		{"unicode little endian", makeExif(binary.LittleEndian, append([]byte("UNICODE\x00"), encodeUTF16(binary.LittleEndian, text)...)), text, true, ""},
+		{"unicode with other byte order", makeExif(binary.LittleEndian, append([]byte("UNICODE\x00"), encodeUTF16(binary.BigEndian, text)...)), text, true, ""},
+		{"unicode with BOM", makeExif(binary.BigEndian, append([]byte("UNICODE\x00\xff\xfe"), encodeUTF16(binary.LittleEndian, text)...)), text, true, ""},
+		{"ascii", makeExif(binary.BigEndian, []byte("ASCII\x00\x00\x00cat, dog\x00")), "cat, dog", true, ""},
+		{"undefined", makeExif(binary.LittleEndian, []byte("\x00\x00\x00\x00\x00\x00\x00\x00cat")), "cat", true, ""},
+		{"without charset", makeExif(binary.LittleEndian, []byte("cat, d")), "cat, d", true, ""},
+		{"no exif ifd", []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x00\x00\x00\x00\x00"), "", false, ""},
+		{"pointer type", []byte("Exif\x00\x00MM\x00\x2a\x00\x00\x00\x08\x00\x01\x87\x69\x00\x01\x00\x00\x00\x01\x1a\x00\x00\x00\x00\x00\x00\x00"), "", false, "invalid EXIF data"},
+		{"byte order", []byte("Exif\x00\x00XX\x00\x2a\x00\x00\x00\x08"), "", false, "invalid EXIF data"},
+		{"offset", []byte("Exif\x00\x00MM\x00\x2a\x00\x00\xff\x08"), "", false, "invalid EXIF data"},
+		{"truncated", makeExif(binary.BigEndian, []byte("ASCII\x00\x00\x00cat"))[:40], "", false, "invalid EXIF data"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comment, found, err := readExifUserComment(test.data)
+			if len(test.err) > 0 {
+				assert.EqualError(t, err, test.err)
+			} else {
+				assert.Equal(t, nil, err)
+			}
+			assert.Equal(t, test.found, found)
+			assert.Equal(t, test.comment, comment)
+		})
+	}
+}
+
+func TestReadXMPUserComment(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		comment string
+		found   bool
+	}{
+		{"element", `<x:xmpmeta xmlns:x="adobe:ns:meta/"><rdf:RDF xmlns:rdf="http://www.w3.org/1999/02/22-rdf-syntax-ns#"><rdf:Description xmlns:exif="http://ns.adobe.com/exif/1.0/"><exif:UserComment><rdf:Alt><rdf:li xml:lang="x-default">cat, &lt;lora:x:1&gt;</rdf:li></rdf:Alt></exif:UserComment></rdf:Description></rdf:RDF></x:xmpmeta>`, "cat, <lora:x:1>", true},
+		{"attribute", `<rdf:RDF><rdf:Description exif:UserComment="cat, dog"/></rdf:RDF>`, "cat, dog", true},
+		{"missing", `<rdf:RDF><rdf:Description/></rdf:RDF>`, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			comment, found := readXMPUserComment([]byte(test.data))
+			assert.Equal(t, test.found, found)
+			assert.Equal(t, test.comment, comment)
+		})
+	}
+}
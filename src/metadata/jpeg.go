@@ -0,0 +1,103 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+var (
+	jpegSignature = []byte{0xff, 0xd8, 0xff}
+	xmpHeader     = []byte("http://ns.adobe.com/xap/1.0/\x00")
+)
+
+const (
+	jpegApp1 = 0xe1
+	jpegSOS  = 0xda
+	jpegEOI  = 0xd9
+)
+
+func IsJPEG(data []byte) bool {
+	return bytes.HasPrefix(data, jpegSignature)
+}
+
+// ReadJPEG extracts EXIF UserComment and XMP from APP1 segments, reading stops at the image data.
+func ReadJPEG(reader io.Reader) (Metadata, error) {
+	metadata := make(Metadata)
+
+	header := make([]byte, 2)
+	if _, err := io.ReadFull(reader, header); err != nil || !bytes.Equal(header, jpegSignature[:2]) {
+		return metadata, errors.New("not a JPEG file")
+	}
+
+	segment := make([]byte, 4)
+	for {
+		if _, err := io.ReadFull(reader, segment); err != nil {
+			return metadata, fmt.Errorf("truncated JPEG file: %w", err)
+		}
+
+		if segment[0] != 0xff {
+			return metadata, errors.New("invalid JPEG marker")
+		}
+
+		marker := segment[1]
+		if marker == jpegSOS || marker == jpegEOI {
+			return metadata, nil
+		}
+
+		length := int64(binary.BigEndian.Uint16(segment[2:]))
+		if length < 2 {
+			return metadata, errors.New("invalid JPEG segment length")
+		}
+
+		if marker != jpegApp1 {
+			if _, err := io.CopyN(io.Discard, reader, length-2); err != nil {
+				return metadata, fmt.Errorf("truncated JPEG file: %w", err)
+			}
+			continue
+		}
+
+		data := make([]byte, length-2)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return metadata, fmt.Errorf("truncated JPEG file: %w", err)
+		}
+
+		if err := metadata.addApp1(data); err != nil {
+			return metadata, err
+		}
+	}
+}
+
+func (metadata Metadata) addApp1(data []byte) error {
+	switch {
+	case bytes.HasPrefix(data, exifHeader):
+		return metadata.addExif(data)
+	case bytes.HasPrefix(data, xmpHeader):
+		metadata.addXMP(data[len(xmpHeader):])
+	}
+
+	return nil
+}
+
+func (metadata Metadata) addExif(data []byte) error {
+	comment, found, err := readExifUserComment(data)
+	if found {
+		metadata[UserCommentKey] = comment
+	}
+
+	return err
+}
+
+// XMP UserComment is used only when EXIF has none.
+func (metadata Metadata) addXMP(data []byte) {
+	metadata[XMPKey] = string(data)
+	if _, found := metadata[UserCommentKey]; found {
+		return
+	}
+
+	if comment, found := readXMPUserComment(data); found {
+		metadata[UserCommentKey] = comment
+	}
+}
@@ -0,0 +1,99 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/jpeg"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeSegment(marker byte, data []byte) []byte {
+	segment := binary.BigEndian.AppendUint16([]byte{0xff, marker}, uint16(len(data)+2))
+
+	return append(segment, data...)
+}
+
+// makeJPEG encodes a 1x1 image and inserts segments right after SOI.
+func makeJPEG(segments ...[]byte) []byte {
+	buffer := &bytes.Buffer{}
+	jpeg.Encode(buffer, image.NewGray(image.Rect(0, 0, 1, 1)), nil)
+	encoded := buffer.Bytes()
+
+	result := append([]byte{}, encoded[:2]...)
+	for _, segment := range segments {
+		result = append(result, segment...)
+	}
+
+	return append(result, encoded[2:]...)
+}
+
+func TestReadJPEG(t *testing.T) {
+	text := "cat, dog\nSteps: 20"
+	xmp := `<x:xmpmeta><exif:UserComment>from xmp</exif:UserComment></x:xmpmeta>`
+
+	tests := []struct {
+		name     string
+		data     []byte
+		metadata Metadata
+	}{
+		{
+			"exif",
+			makeJPEG(makeSegment(jpegApp1, makeExif(binary.BigEndian, append([]byte("UNICODE\x00"), encodeUTF16(binary.BigEndian, text)...)))),
+			Metadata{UserCommentKey: text},
+		},
+		{
+			"xmp",
+			makeJPEG(makeSegment(jpegApp1, append(append([]byte{}, xmpHeader...), xmp...))),
+			Metadata{UserCommentKey: "from xmp", XMPKey: xmp},
+		},
+		{
+			"exif before xmp",
+			makeJPEG(
+				makeSegment(jpegApp1, makeExif(binary.LittleEndian, []byte("ASCII\x00\x00\x00from exif"))),
+				makeSegment(jpegApp1, append(append([]byte{}, xmpHeader...), xmp...)),
+			),
+			Metadata{UserCommentKey: "from exif", XMPKey: xmp},
+		},
+		{
+			"none",
+			makeJPEG(),
+			Metadata{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata, err := ReadJPEG(bytes.NewReader(test.data))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.metadata, metadata)
+
+			read, err := Read(bytes.NewReader(test.data))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.metadata, read)
+		})
+	}
+}
+
+func TestReadJPEGErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"not jpeg", makePNG(), "not a JPEG file"},
+		{"truncated", []byte{0xff, 0xd8, 0xff, 0xe1, 0x00, 0x10, 'E'}, "truncated JPEG file: unexpected EOF"},
+		{"marker", []byte{0xff, 0xd8, 0x00, 0xe1, 0x00, 0x02}, "invalid JPEG marker"},
+		{"length", []byte{0xff, 0xd8, 0xff, 0xe0, 0x00, 0x01}, "invalid JPEG segment length"},
+		{"exif", makeJPEG(makeSegment(jpegApp1, []byte("Exif\x00\x00XX"))), "invalid EXIF data"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadJPEG(bytes.NewReader(test.data))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
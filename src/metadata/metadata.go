@@ -0,0 +1,59 @@
+package metadata
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"strings"
+)
+
+const (
+	ParametersKey  = "parameters"
+	UserCommentKey = "UserComment"
+	XMPKey         = "XMP"
+)
+
+// Metadata maps text chunk keywords (parameters, prompt, workflow, Comment, ...) to their values.
+// JPEG and WebP images have UserComment and XMP keys instead.
+type Metadata map[string]string
+
+// Read detects PNG, JPEG or WebP format and extracts its metadata.
+func Read(reader io.Reader) (Metadata, error) {
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return make(Metadata), err
+	}
+
+	switch {
+	case IsPNG(data):
+		return ReadPNG(bytes.NewReader(data))
+	case IsJPEG(data):
+		return ReadJPEG(bytes.NewReader(data))
+	case IsWebP(data):
+		return ReadWebP(bytes.NewReader(data))
+	default:
+		return make(Metadata), errors.New("unsupported image format")
+	}
+}
+
+func IsImage(data []byte) bool {
+	return IsPNG(data) || IsJPEG(data) || IsWebP(data)
+}
+
+// Parameters returns A1111 generation info stored in the metadata.
+func (metadata Metadata) Parameters() (string, bool) {
+	if parameters, found := metadata[ParametersKey]; found {
+		return parameters, true
+	}
+
+	parameters, found := metadata[UserCommentKey]
+
+	return parameters, found
+}
+
+// IsNovelAI checks for the "Software" chunk or a JSON "Comment" with the negative prompt.
+func (metadata Metadata) IsNovelAI() bool {
+	comment := strings.TrimSpace(metadata["Comment"])
+
+	return metadata["Software"] == "NovelAI" || (strings.HasPrefix(comment, "{") && strings.Contains(comment, `"uc"`))
+}
@@ -0,0 +1,164 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+)
+
+var pngSignature = []byte("\x89PNG\r\n\x1a\n")
+
+// Text chunks larger than this are rejected instead of being read into memory.
+const maxTextChunkLength = 64 << 20
+
+type pngChunk struct {
+	kind string
+	data []byte
+}
+
+func IsPNG(data []byte) bool {
+	return bytes.HasPrefix(data, pngSignature)
+}
+
+// ReadPNG extracts tEXt, zTXt and iTXt chunks without decoding the image.
+func ReadPNG(reader io.Reader) (Metadata, error) {
+	metadata := make(Metadata)
+
+	err := readPNGChunks(reader, func(kind string) bool {
+		return kind == "tEXt" || kind == "zTXt" || kind == "iTXt"
+	}, func(chunk *pngChunk) error {
+		keyword, text, err := decodePNGText(chunk)
+		if err != nil {
+			return err
+		}
+
+		metadata[keyword] = text
+
+		return nil
+	})
+
+	return metadata, err
+}
+
+// readPNGChunks calls handle for every chunk accepted by wanted, other chunks are skipped.
+func readPNGChunks(reader io.Reader, wanted func(kind string) bool, handle func(chunk *pngChunk) error) error {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(reader, signature); err != nil || !IsPNG(signature) {
+		return errors.New("not a PNG file")
+	}
+
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return fmt.Errorf("truncated PNG file: %w", err)
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:])
+
+		if !wanted(kind) {
+			// skip data and CRC of chunks we don't need
+			if _, err := io.CopyN(io.Discard, reader, int64(length)+4); err != nil {
+				return fmt.Errorf("truncated PNG file: %w", err)
+			}
+		} else {
+			if length > maxTextChunkLength {
+				return fmt.Errorf("%s chunk is too large", kind)
+			}
+
+			data := make([]byte, length+4)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return fmt.Errorf("truncated PNG file: %w", err)
+			}
+
+			checksum := crc32.NewIEEE()
+			checksum.Write(header[4:])
+			checksum.Write(data[:length])
+			if checksum.Sum32() != binary.BigEndian.Uint32(data[length:]) {
+				return fmt.Errorf("invalid %s chunk CRC", kind)
+			}
+
+			if err := handle(&pngChunk{kind: kind, data: data[:length]}); err != nil {
+				return err
+			}
+		}
+
+		if kind == "IEND" {
+			return nil
+		}
+	}
+}
+
+func decodePNGText(chunk *pngChunk) (string, string, error) {
+	keyword, data, found := bytes.Cut(chunk.data, []byte{0})
+	if !found || len(keyword) == 0 {
+		return "", "", fmt.Errorf("invalid %s chunk", chunk.kind)
+	}
+
+	switch chunk.kind {
+	case "tEXt":
+		return latin1ToString(keyword), latin1ToString(data), nil
+	case "zTXt":
+		if len(data) < 1 || data[0] != 0 {
+			return "", "", errors.New("unsupported zTXt compression method")
+		}
+
+		text, err := inflate(data[1:])
+		if err != nil {
+			return "", "", fmt.Errorf("invalid zTXt chunk: %w", err)
+		}
+
+		return latin1ToString(keyword), latin1ToString(text), nil
+	default:
+		// iTXt: compression flag, compression method, language tag, translated keyword, text
+		if len(data) < 2 {
+			return "", "", errors.New("invalid iTXt chunk")
+		}
+
+		compressed, method := data[0], data[1]
+		_, data, found = bytes.Cut(data[2:], []byte{0})
+		if found {
+			_, data, found = bytes.Cut(data, []byte{0})
+		}
+		if !found {
+			return "", "", errors.New("invalid iTXt chunk")
+		}
+
+		if compressed == 1 {
+			if method != 0 {
+				return "", "", errors.New("unsupported iTXt compression method")
+			}
+
+			text, err := inflate(data)
+			if err != nil {
+				return "", "", fmt.Errorf("invalid iTXt chunk: %w", err)
+			}
+			data = text
+		}
+
+		return latin1ToString(keyword), string(data), nil
+	}
+}
+
+func inflate(data []byte) ([]byte, error) {
+	reader, err := zlib.NewReader(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	defer reader.Close()
+
+	return io.ReadAll(io.LimitReader(reader, maxTextChunkLength))
+}
+
+func latin1ToString(data []byte) string {
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+
+	return string(runes)
+}
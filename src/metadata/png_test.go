@@ -0,0 +1,94 @@
+package metadata
+
+import (
+	"bytes"
+	"compress/zlib"
+	"image"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func deflate(text string) []byte {
+	buffer := &bytes.Buffer{}
+	writer := zlib.NewWriter(buffer)
+	writer.Write([]byte(text))
+	writer.Close()
+
+	return buffer.Bytes()
+}
+
+// makePNG encodes a 1x1 image and inserts chunks right after IHDR.
+func makePNG(chunks ...[]byte) []byte {
+	buffer := &bytes.Buffer{}
+	png.Encode(buffer, image.NewGray(image.Rect(0, 0, 1, 1)))
+	encoded := buffer.Bytes()
+
+	// signature (8) + IHDR length, type, data (13) and CRC
+	headerEnd := 8 + 4 + 4 + 13 + 4
+	result := append([]byte{}, encoded[:headerEnd]...)
+	for _, chunk := range chunks {
+		result = append(result, chunk...)
+	}
+
+	return append(result, encoded[headerEnd:]...)
+}
+
+func TestReadPNG(t *testing.T) {
+	data := makePNG(
+		makePNGChunk("tEXt", []byte("parameters\x00cat, dog\nSteps: 20, Sampler: Euler, Seed: 1")),
+		makePNGChunk("tEXt", []byte("Comment\x00caf\xe9")),
+		makePNGChunk("zTXt", append([]byte("prompt\x00\x00"), deflate(`{"3": {"class_type": "KSampler"}}`)...)),
+		makePNGChunk("iTXt", []byte("workflow\x00\x00\x00en\x00Workflow\x00{\"nodes\": []}")),
+		makePNGChunk("iTXt", append([]byte("Description\x00\x01\x00\x00\x00"), deflate("кот")...)),
+	)
+
+	metadata, err := ReadPNG(bytes.NewReader(data))
+	assert.Equal(t, nil, err)
+	assert.Equal(t, Metadata{
+		"parameters":  "cat, dog\nSteps: 20, Sampler: Euler, Seed: 1",
+		"Comment":     "café",
+		"prompt":      `{"3": {"class_type": "KSampler"}}`,
+		"workflow":    `{"nodes": []}`,
+		"Description": "кот",
+	}, metadata)
+
+	parameters, found := metadata.Parameters()
+	assert.Equal(t, true, found)
+	assert.Equal(t, "cat, dog\nSteps: 20, Sampler: Euler, Seed: 1", parameters)
+}
+
+func TestReadPNGErrors(t *testing.T) {
+	corrupted := makePNGChunk("tEXt", []byte("parameters\x00cat"))
+	corrupted[len(corrupted)-1] ^= 0xff
+
+	valid := makePNG()
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"not png", []byte("GIF89a"), "not a PNG file"},
+		{"truncated", valid[:len(valid)-6], "truncated PNG file: unexpected EOF"},
+		{"crc", makePNG(corrupted), "invalid tEXt chunk CRC"},
+		{"keyword", makePNG(makePNGChunk("tEXt", []byte("cat"))), "invalid tEXt chunk"},
+		{"zTXt method", makePNG(makePNGChunk("zTXt", []byte("prompt\x00\x01"))), "unsupported zTXt compression method"},
+		{"iTXt", makePNG(makePNGChunk("iTXt", []byte("prompt\x00\x00\x00en"))), "invalid iTXt chunk"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadPNG(bytes.NewReader(test.data))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestIsNovelAI(t *testing.T) {
+	assert.Equal(t, true, Metadata{"Software": "NovelAI", "Description": "cat"}.IsNovelAI())
+	assert.Equal(t, true, Metadata{"Comment": `{"steps": 28, "uc": "lowres"}`}.IsNovelAI())
+	assert.Equal(t, false, Metadata{"Comment": "made with love"}.IsNovelAI())
+	assert.Equal(t, false, Metadata{"parameters": "cat"}.IsNovelAI())
+}
@@ -0,0 +1,140 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"sort"
+	"unicode/utf8"
+)
+
+// WritePNG copies the PNG chunk by chunk and stores text in tEXt chunks, or iTXt when
+// the value is not Latin-1. Chunks with the same keywords are replaced, other text
+// chunks are dropped unless keepText is set. Image data is not re-encoded.
+func WritePNG(reader io.Reader, writer io.Writer, text Metadata, keepText bool) error {
+	signature := make([]byte, len(pngSignature))
+	if _, err := io.ReadFull(reader, signature); err != nil || !IsPNG(signature) {
+		return errors.New("not a PNG file")
+	}
+
+	if _, err := writer.Write(signature); err != nil {
+		return err
+	}
+
+	written := false
+	header := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, header); err != nil {
+			return fmt.Errorf("truncated PNG file: %w", err)
+		}
+
+		length := binary.BigEndian.Uint32(header[:4])
+		kind := string(header[4:])
+
+		// new text goes before image data so that streaming readers find it
+		if !written && (kind == "IDAT" || kind == "IEND") {
+			if err := writeTextChunks(writer, text); err != nil {
+				return err
+			}
+			written = true
+		}
+
+		if kind == "tEXt" || kind == "zTXt" || kind == "iTXt" {
+			if length > maxTextChunkLength {
+				return fmt.Errorf("%s chunk is too large", kind)
+			}
+
+			data := make([]byte, length+4)
+			if _, err := io.ReadFull(reader, data); err != nil {
+				return fmt.Errorf("truncated PNG file: %w", err)
+			}
+
+			keyword, _, _ := bytes.Cut(data[:length], []byte{0})
+			if _, replaced := text[latin1ToString(keyword)]; replaced || !keepText {
+				continue
+			}
+
+			if _, err := writer.Write(header); err != nil {
+				return err
+			}
+
+			if _, err := writer.Write(data); err != nil {
+				return err
+			}
+		} else {
+			if _, err := writer.Write(header); err != nil {
+				return err
+			}
+
+			if _, err := io.CopyN(writer, reader, int64(length)+4); err != nil {
+				return fmt.Errorf("truncated PNG file: %w", err)
+			}
+		}
+
+		if kind == "IEND" {
+			return nil
+		}
+	}
+}
+
+// WritePNGParameters replaces A1111 generation info keeping other chunks.
+func WritePNGParameters(reader io.Reader, writer io.Writer, parameters string) error {
+	return WritePNG(reader, writer, Metadata{ParametersKey: parameters}, true)
+}
+
+func writeTextChunks(writer io.Writer, text Metadata) error {
+	keywords := make([]string, 0, len(text))
+	for keyword := range text {
+		keywords = append(keywords, keyword)
+	}
+	sort.Strings(keywords)
+
+	for _, keyword := range keywords {
+		encodedKeyword, ok := stringToLatin1(keyword)
+		if !ok || len(encodedKeyword) == 0 || len(encodedKeyword) > 79 {
+			return fmt.Errorf("invalid PNG text keyword %q", keyword)
+		}
+
+		var chunk []byte
+		if value, ok := stringToLatin1(text[keyword]); ok {
+			chunk = makePNGChunk("tEXt", append(append(encodedKeyword, 0), value...))
+		} else {
+			// keyword, compression flag and method, empty language tag and translated keyword
+			data := append(encodedKeyword, 0, 0, 0, 0, 0)
+			chunk = makePNGChunk("iTXt", append(data, text[keyword]...))
+		}
+
+		if _, err := writer.Write(chunk); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func makePNGChunk(kind string, data []byte) []byte {
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(data)))
+	chunk = append(chunk, kind...)
+	chunk = append(chunk, data...)
+
+	return binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(chunk[4:]))
+}
+
+func stringToLatin1(text string) ([]byte, bool) {
+	if !utf8.ValidString(text) {
+		return nil, false
+	}
+
+	encoded := make([]byte, 0, len(text))
+	for _, char := range text {
+		if char > 0xff {
+			return nil, false
+		}
+		encoded = append(encoded, byte(char))
+	}
+
+	return encoded, true
+}
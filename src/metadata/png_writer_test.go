@@ -0,0 +1,102 @@
+package metadata
+
+import (
+	"bytes"
+	"image/png"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWritePNG(t *testing.T) {
+	source := makePNG(
+		makePNGChunk("tEXt", []byte("parameters\x00old prompt\nSteps: 20")),
+		makePNGChunk("zTXt", append([]byte("prompt\x00\x00"), deflate(`{}`)...)),
+		makePNGChunk("iTXt", []byte("Comment\x00\x00\x00\x00\x00старый")),
+	)
+
+	tests := []struct {
+		name     string
+		text     Metadata
+		keep     bool
+		metadata Metadata
+	}{
+		{
+			"replace",
+			Metadata{ParametersKey: "new prompt, (cat)\nSteps: 30"},
+			true,
+			Metadata{ParametersKey: "new prompt, (cat)\nSteps: 30", "prompt": "{}", "Comment": "старый"},
+		},
+		{
+			"unicode",
+			Metadata{ParametersKey: "кот", "Comment": "café"},
+			true,
+			Metadata{ParametersKey: "кот", "Comment": "café", "prompt": "{}"},
+		},
+		{
+			"strip",
+			Metadata{ParametersKey: "cat"},
+			false,
+			Metadata{ParametersKey: "cat"},
+		},
+		{
+			"remove all",
+			Metadata{},
+			false,
+			Metadata{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			buffer := &bytes.Buffer{}
+			err := WritePNG(bytes.NewReader(source), buffer, test.text, test.keep)
+			assert.Equal(t, nil, err)
+
+			metadata, err := ReadPNG(bytes.NewReader(buffer.Bytes()))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.metadata, metadata)
+
+			// image data stays valid
+			_, err = png.Decode(bytes.NewReader(buffer.Bytes()))
+			assert.Equal(t, nil, err)
+		})
+	}
+}
+
+func TestWritePNGParameters(t *testing.T) {
+	source := makePNG()
+
+	buffer := &bytes.Buffer{}
+	err := WritePNGParameters(bytes.NewReader(source), buffer, "cat\nSteps: 20")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, len(source)+len(makePNGChunk("tEXt", []byte("parameters\x00cat\nSteps: 20"))), buffer.Len())
+
+	rewritten := &bytes.Buffer{}
+	err = WritePNGParameters(bytes.NewReader(buffer.Bytes()), rewritten, "cat\nSteps: 20")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, buffer.Bytes(), rewritten.Bytes())
+}
+
+func TestWritePNGErrors(t *testing.T) {
+	valid := makePNG()
+
+	tests := []struct {
+		name string
+		data []byte
+		text Metadata
+		err  string
+	}{
+		{"not png", []byte("GIF89a"), Metadata{}, "not a PNG file"},
+		{"truncated", valid[:len(valid)-6], Metadata{}, "truncated PNG file: unexpected EOF"},
+		{"keyword", valid, Metadata{"": "cat"}, `invalid PNG text keyword ""`},
+		{"unicode keyword", valid, Metadata{"кот": "cat"}, `invalid PNG text keyword "кот"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			err := WritePNG(bytes.NewReader(test.data), &bytes.Buffer{}, test.text, true)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
@@ -0,0 +1,63 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+func IsWebP(data []byte) bool {
+	return len(data) >= 12 && string(data[:4]) == "RIFF" && string(data[8:12]) == "WEBP"
+}
+
+// ReadWebP extracts EXIF UserComment and XMP from EXIF and "XMP " chunks.
+func ReadWebP(reader io.Reader) (Metadata, error) {
+	metadata := make(Metadata)
+
+	header := make([]byte, 12)
+	if _, err := io.ReadFull(reader, header); err != nil || !IsWebP(header) {
+		return metadata, errors.New("not a WebP file")
+	}
+
+	chunk := make([]byte, 8)
+	for {
+		if _, err := io.ReadFull(reader, chunk); err != nil {
+			if err == io.EOF {
+				return metadata, nil
+			}
+			return metadata, fmt.Errorf("truncated WebP file: %w", err)
+		}
+
+		kind := string(chunk[:4])
+		length := int64(binary.LittleEndian.Uint32(chunk[4:]))
+		// chunks are padded to even size
+		padded := length + length%2
+
+		if kind != "EXIF" && kind != "XMP " {
+			if _, err := io.CopyN(io.Discard, reader, padded); err != nil {
+				return metadata, fmt.Errorf("truncated WebP file: %w", err)
+			}
+			continue
+		}
+
+		if length > maxTextChunkLength {
+			return metadata, fmt.Errorf("%s chunk is too large", kind)
+		}
+
+		data := make([]byte, padded)
+		if _, err := io.ReadFull(reader, data); err != nil {
+			return metadata, fmt.Errorf("truncated WebP file: %w", err)
+		}
+		data = data[:length]
+
+		if kind == "EXIF" {
+			if err := metadata.addExif(data); err != nil {
+				return metadata, err
+			}
+		} else {
+			metadata.addXMP(bytes.TrimRight(data, "\x00"))
+		}
+	}
+}
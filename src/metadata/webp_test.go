@@ -0,0 +1,105 @@
+package metadata
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func makeRIFFChunk(kind string, data []byte) []byte {
+	chunk := binary.LittleEndian.AppendUint32([]byte(kind), uint32(len(data)))
+	chunk = append(chunk, data...)
+	if len(data)%2 == 1 {
+		chunk = append(chunk, 0)
+	}
+
+	return chunk
+}
+
+func makeWebP(chunks ...[]byte) []byte {
+	body := []byte("WEBP")
+	for _, chunk := range chunks {
+		body = append(body, chunk...)
+	}
+
+	return append(binary.LittleEndian.AppendUint32([]byte("RIFF"), uint32(len(body))), body...)
+}
+
+func TestReadWebP(t *testing.T) {
+	text := "cat, (dog)\nNegative prompt: blurry"
+	xmp := `<x:xmpmeta><rdf:Description exif:UserComment="from xmp"/></x:xmpmeta>`
+	image := makeRIFFChunk("VP8L", []byte{0x2f, 0x00, 0x00, 0x00, 0x00})
+
+	tests := []struct {
+		name     string
+		data     []byte
+		metadata Metadata
+	}{
+		{
+			"exif with header",
+			makeWebP(image, makeRIFFChunk("EXIF", makeExif(binary.LittleEndian, append([]byte("UNICODE\x00"), encodeUTF16(binary.LittleEndian, text)...)))),
+			Metadata{UserCommentKey: text},
+		},
+		{
+			"exif without header",
+			makeWebP(image, makeRIFFChunk("EXIF", makeExif(binary.BigEndian, []byte("ASCII\x00\x00\x00cat"))[len(exifHeader):])),
+			Metadata{UserCommentKey: "cat"},
+		},
+		{
+			"xmp",
+			makeWebP(makeRIFFChunk("VP8X", make([]byte, 10)), image, makeRIFFChunk("XMP ", []byte(xmp))),
+			Metadata{UserCommentKey: "from xmp", XMPKey: xmp},
+		},
+		{
+			"none",
+			makeWebP(image),
+			Metadata{},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			metadata, err := ReadWebP(bytes.NewReader(test.data))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.metadata, metadata)
+
+			read, err := Read(bytes.NewReader(test.data))
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.metadata, read)
+		})
+	}
+}
+
+func TestReadWebPErrors(t *testing.T) {
+	truncated := makeWebP(makeRIFFChunk("EXIF", []byte("Exif")))
+
+	tests := []struct {
+		name string
+		data []byte
+		err  string
+	}{
+		{"not webp", []byte("RIFF\x00\x00\x00\x00WAVE"), "not a WebP file"},
+		{"truncated", truncated[:len(truncated)-2], "truncated WebP file: unexpected EOF"},
+		{"exif", makeWebP(makeRIFFChunk("EXIF", []byte("MM\x00\x00"))), "invalid EXIF data"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			_, err := ReadWebP(bytes.NewReader(test.data))
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestRead(t *testing.T) {
+	_, err := Read(bytes.NewReader([]byte("GIF89a")))
+	assert.EqualError(t, err, "unsupported image format")
+
+	metadata, err := Read(bytes.NewReader(makeWebP(makeRIFFChunk("EXIF", makeExif(binary.BigEndian, []byte("ASCII\x00\x00\x00cat"))))))
+	assert.Equal(t, nil, err)
+	parameters, found := metadata.Parameters()
+	assert.Equal(t, true, found)
+	assert.Equal(t, "cat", parameters)
+}
@@ -0,0 +1,51 @@
+package metadata
+
+import (
+	"encoding/xml"
+	"strings"
+)
+
+// readXMPUserComment returns exif:UserComment of an XMP packet, written either
+// as an attribute or as an element with optional rdf:Alt/rdf:li wrappers.
+func readXMPUserComment(data []byte) (string, bool) {
+	decoder := xml.NewDecoder(strings.NewReader(string(data)))
+	decoder.Strict = false
+
+	depth := 0
+	var text strings.Builder
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return "", false
+		}
+
+		switch token := token.(type) {
+		case xml.StartElement:
+			if depth > 0 {
+				depth++
+				continue
+			}
+
+			for _, attribute := range token.Attr {
+				if attribute.Name.Local == "UserComment" {
+					return attribute.Value, true
+				}
+			}
+
+			if token.Name.Local == "UserComment" {
+				depth = 1
+			}
+		case xml.EndElement:
+			if depth > 0 {
+				depth--
+				if depth == 0 {
+					return strings.TrimSpace(text.String()), true
+				}
+			}
+		case xml.CharData:
+			if depth > 0 {
+				text.Write(token)
+			}
+		}
+	}
+}
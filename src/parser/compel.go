@@ -0,0 +1,291 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/junte/stable-diffusion-prompt-parser/src/reader"
+)
+
+func isCompelEmphasis(token string) bool {
+	return token != "" && strings.Trim(token, "+-") == ""
+}
+
+func (parser *PromptParser) parseCompelSuffix(reader *reader.TokenReader, content *prompt) *prompt {
+	token := reader.GetToken()
+	if isCompelEmphasis(token) {
+		reader.NextToken()
+
+		weight := 1.0
+		for _, sign := range token {
+			if sign == '+' {
+				weight *= 1.1
+			} else {
+				weight *= 0.9
+			}
+		}
+
+		return &prompt{
+			kind:     customWeight,
+			weight:   weight,
+			contents: []*prompt{content},
+		}
+	}
+
+	if weight, err := strconv.ParseFloat(token, 64); err == nil {
+		reader.NextToken()
+		return &prompt{
+			kind:     customWeight,
+			weight:   weight,
+			contents: []*prompt{content},
+		}
+	}
+
+	return content
+}
+
+func (parser *PromptParser) parseCompelTagPrompt(reader *reader.TokenReader) []*prompt {
+	tokens := []string{}
+	for {
+		token := reader.GetToken()
+		if token == "" || token == "," || token == "(" || token == ")" || strings.HasPrefix(token, `"`) || isCompelEmphasis(token) {
+			break
+		}
+
+		tokens = append(tokens, parser.escapeToken(token))
+		reader.NextToken()
+	}
+
+	if !isCompelEmphasis(reader.GetToken()) {
+		return []*prompt{{kind: tag, name: strings.Join(tokens, " "), tokens: tokens}}
+	}
+
+	// red hair++ only emphasizes the last word
+	contents := []*prompt{}
+	last := len(tokens) - 1
+	if last > 0 {
+		contents = append(contents, &prompt{kind: tag, name: strings.Join(tokens[:last], " "), tokens: tokens[:last]})
+	}
+
+	return append(contents, parser.parseCompelSuffix(reader, &prompt{kind: tag, name: tokens[last], tokens: tokens[last:]}))
+}
+
+func (parser *PromptParser) parseCompelFragment(reader *reader.TokenReader) (*prompt, error) {
+	text := strings.TrimPrefix(reader.GetToken(), `"`)
+	if strings.HasSuffix(text, `"`) && !strings.HasSuffix(text, `\"`) {
+		text = strings.TrimSuffix(text, `"`)
+	}
+	reader.NextToken()
+
+	fragment, err := parser.parseCompel(text)
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	return &prompt{
+		kind:     group,
+		prefix:   `"`,
+		contents: fragment.contents,
+	}, nil
+}
+
+func (parser *PromptParser) parseCompelArguments(reader *reader.TokenReader) []float64 {
+	reader.NextToken()
+	if reader.GetToken() != "(" {
+		return nil
+	}
+
+	reader.NextToken()
+	arguments := []float64{}
+	for token := reader.GetToken(); token != ")" && token != ""; token = reader.GetToken() {
+		// non-numeric arguments (e.g. no_normalize) do not change weights
+		if number, err := strconv.ParseFloat(token, 64); err == nil {
+			arguments = append(arguments, number)
+		}
+		reader.NextToken()
+	}
+	reader.NextToken()
+
+	return arguments
+}
+
+func (parser *PromptParser) parseCompelGroup(reader *reader.TokenReader) (*prompt, error) {
+	reader.NextToken()
+	contents, err := parser.parseCompelContents(reader)
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	// RECOVER: missing )
+	if reader.GetToken() == ")" {
+		reader.NextToken()
+	}
+
+	switch reader.GetToken() {
+	case ".blend", ".and":
+		kind, partKind := blend, customWeight
+		if reader.GetToken() == ".and" {
+			kind, partKind = conjunction, composable
+		}
+
+		weights := parser.parseCompelArguments(reader)
+		parts := make([]*prompt, len(contents))
+		for i, content := range contents {
+			weight := 1.0
+			if i < len(weights) {
+				weight = weights[i]
+			}
+
+			parts[i] = &prompt{
+				kind:     partKind,
+				weight:   weight,
+				contents: []*prompt{content},
+			}
+		}
+
+		return &prompt{
+			kind:     kind,
+			contents: parts,
+		}, nil
+	}
+
+	return parser.parseCompelSuffix(reader, &prompt{
+		kind:     group,
+		contents: contents,
+	}), nil
+}
+
+func (parser *PromptParser) parseCompelContents(reader *reader.TokenReader) (contents []*prompt, err error) {
+	for {
+		token := reader.GetToken()
+		switch {
+		case token == ")" || token == "":
+			return contents, nil
+		case token == "," || isCompelEmphasis(token):
+			reader.NextToken()
+		case token == "(":
+			content, err := parser.parseCompelGroup(reader)
+			if err != nil {
+				return nil, err
+			}
+
+			contents = append(contents, content)
+		case strings.HasPrefix(token, `"`):
+			content, err := parser.parseCompelFragment(reader)
+			if err != nil {
+				return nil, err
+			}
+
+			contents = append(contents, parser.parseCompelSuffix(reader, content))
+		default:
+			contents = append(contents, parser.parseCompelTagPrompt(reader)...)
+		}
+	}
+}
+
+func (parser *PromptParser) parseCompel(input string) (*prompt, error) {
+	prompt := &prompt{}
+	reader := reader.NewCompelTokenReader(input)
+
+	for {
+		switch reader.GetToken() {
+		case ")":
+			reader.NextToken()
+			continue
+		case "":
+			// ("a", "b").and() is the same as a AND b
+			if len(prompt.contents) == 1 && prompt.contents[0].kind == conjunction {
+				prompt.contents = prompt.contents[0].contents
+			}
+
+			return prompt, nil
+		default:
+			contents, err := parser.parseCompelContents(reader)
+			if err != nil {
+				return prompt, err
+			}
+
+			prompt.contents = append(prompt.contents, contents...)
+		}
+	}
+}
+
+func escapeCompelName(name string) string {
+	return regexp.MustCompile(`([\\()",])`).ReplaceAllString(name, `\$1`)
+}
+
+func compelWeight(weight float64) string {
+	for count := 1; count <= 5; count++ {
+		if math.Abs(weight-math.Pow(1.1, float64(count))) < 1e-9 {
+			return strings.Repeat("+", count)
+		}
+
+		if math.Abs(weight-math.Pow(0.9, float64(count))) < 1e-9 {
+			return strings.Repeat("-", count)
+		}
+	}
+
+	return fmt.Sprintf("%v", weight)
+}
+
+func (parser *PromptParser) compelPartsToString(parts []*prompt, method string) string {
+	contents, weights := make([]string, len(parts)), make([]string, len(parts))
+	weighted := method == "blend"
+	for i, part := range parts {
+		contents[i] = parser.compelContentsToString(part.contents)
+		weights[i] = fmt.Sprintf("%v", part.weight)
+		weighted = weighted || part.weight != 1
+	}
+
+	if !weighted {
+		weights = nil
+	}
+
+	return "(" + strings.Join(contents, ", ") + ")." + method + "(" + strings.Join(weights, ", ") + ")"
+}
+
+func (parser *PromptParser) compelContentsToString(contents []*prompt) string {
+	if len(contents) > 0 && contents[0].kind == composable {
+		return parser.compelPartsToString(contents, "and")
+	}
+
+	results := make([]string, len(contents))
+	for i, content := range contents {
+		switch content.kind {
+		case positiveWeight:
+			results[i] = "(" + parser.compelContentsToString(content.contents) + ")+"
+		case negativeWeight:
+			results[i] = "(" + parser.compelContentsToString(content.contents) + ")-"
+		case customWeight:
+			inner := parser.compelContentsToString(content.contents)
+			weight := compelWeight(content.weight)
+			single := len(content.contents) == 1
+			switch {
+			case single && content.contents[0].kind == group:
+				// (red hair)++ and ("a cat")1.2 already have their brackets
+			case single && isCompelEmphasis(weight) && content.contents[0].kind == tag && len(content.contents[0].tokens) == 1:
+				// hair++
+			default:
+				inner = "(" + inner + ")"
+			}
+			results[i] = inner + weight
+		case group:
+			if content.prefix == `"` {
+				results[i] = `"` + parser.compelContentsToString(content.contents) + `"`
+			} else {
+				results[i] = "(" + parser.compelContentsToString(content.contents) + ")"
+			}
+		case blend:
+			results[i] = parser.compelPartsToString(content.contents, "blend")
+		case conjunction:
+			results[i] = parser.compelPartsToString(content.contents, "and")
+		default:
+			results[i] = escapeCompelName(content.name)
+		}
+	}
+
+	return strings.Join(results, ", ")
+}
@@ -0,0 +1,91 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseCompelPrompt(t *testing.T) {
+	tests := []struct {
+		input  string
+		result ParsedPrompt
+	}{
+		{
+			"red hair++, cat-",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "red", Weight: 1},
+					{Tag: "hair", Weight: 1.2100000000000002},
+					{Tag: "cat", Weight: 0.9},
+				},
+			},
+		},
+		{
+			"(red hair)--, (blue eyes)1.3, (smile)",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "red hair", Weight: 0.81},
+					{Tag: "blue eyes", Weight: 1.3},
+					{Tag: "smile", Weight: 1},
+				},
+			},
+		},
+		{
+			`("a cat", "a dog+").blend(0.7, 0.3)`,
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "a cat", Weight: 0.7},
+					{Tag: "a", Weight: 0.3},
+					{Tag: "dog", Weight: 0.33},
+				},
+			},
+		},
+		{
+			`("a castle", "a dragon").and(1, 0.5)`,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "a castle", Weight: 1}, {Tag: "a dragon", Weight: 1}},
+				SubPrompts: []*SubPrompt{
+					{Weight: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "a castle", Weight: 1}}}},
+					{Weight: 0.5, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "a dragon", Weight: 1}}}},
+				},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+	parser.SetDialect(Compel)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParsePrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, *result)
+		})
+	}
+}
+
+func TestCompelPromptToString(t *testing.T) {
+	tests := []struct {
+		input  string
+		result string
+	}{
+		{"red  hair++ ,, cat-", "red, hair++, cat-"},
+		{"( red hair )1.3", "(red hair)1.3"},
+		{"(red hair)+", "(red hair)+"},
+		{`( "a cat" , "a dog" ).blend( 0.7 , 0.3 )`, `("a cat", "a dog").blend(0.7, 0.3)`},
+		{`("a castle", "a dragon").and()`, `("a castle", "a dragon").and()`},
+		{`"a cat"1.2`, `"a cat"1.2`},
+	}
+
+	parser := NewPromptParser()
+	parser.SetDialect(Compel)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.BeautifyPrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
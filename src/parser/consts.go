@@ -5,6 +5,18 @@ const (
 	positiveWeight = "pw"
 	negativeWeight = "nw"
 	customWeight   = "cw"
+	scheduled      = "sched"
+	alternate      = "alt"
+	composable     = "and"
+	conjunction    = "conj"
+	blend          = "blend"
+	group          = "group"
+	phrase         = "phrase"
+	chunkBreak     = "break"
+	regionBreak    = "region"
+	embedding      = "embedding"
+	wildcard       = "wildcard"
 	lora           = "lora"
 	hypernet       = "hypernet"
+	extraNetwork   = "network"
 )
@@ -0,0 +1,265 @@
+package parser
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+type ConvertedPrompt struct {
+	Prompt      string         `json:"prompt"`
+	Loras       []*PromptModel `json:"loras,omitempty"`
+	Hypernets   []*PromptModel `json:"hypernets,omitempty"`
+	Unsupported []string       `json:"unsupported,omitempty"`
+}
+
+type converter struct {
+	parser       *PromptParser
+	to           Dialect
+	sourceFactor float64
+	targetFactor float64
+	converted    *ConvertedPrompt
+}
+
+type weightedContent struct {
+	content *prompt
+	weight  float64
+}
+
+func (parser *PromptParser) Convert(input string, from Dialect, to Dialect) (*ConvertedPrompt, error) {
+	for _, dialect := range []Dialect{from, to} {
+		if !dialect.isKnown() {
+			return &ConvertedPrompt{}, fmt.Errorf("unknown dialect %q", dialect)
+		}
+	}
+
+	source, target := *parser, *parser
+	source.dialect, target.dialect = from, to
+
+	prompt, err := source.parse(input)
+	if err != nil {
+		return &ConvertedPrompt{}, err
+	}
+
+	converter := &converter{
+		parser:       &target,
+		to:           to,
+		sourceFactor: source.weightMultiplier(),
+		targetFactor: target.weightMultiplier(),
+		converted:    &ConvertedPrompt{},
+	}
+
+	prompt.contents = converter.convertContents(prompt.contents, 1)
+	if to != Midjourney {
+		for _, argument := range prompt.arguments {
+			converter.unsupported("parameter --" + argument)
+		}
+		prompt.arguments = nil
+	}
+
+	converter.converted.Prompt = target.toString(prompt)
+
+	return converter.converted, nil
+}
+
+func (converter *converter) unsupported(construct string) {
+	converter.converted.Unsupported = append(converter.converted.Unsupported, fmt.Sprintf("%s is not supported by %s", construct, converter.to))
+}
+
+func (converter *converter) convertContents(contents []*prompt, weight float64) []*prompt {
+	items := converter.collect(contents, weight)
+
+	// consecutive tags with the same weight share one emphasis: (abc, xyz:1.2)
+	result := []*prompt{}
+	for start := 0; start < len(items); {
+		end := start + 1
+		for end < len(items) && items[start].weight == items[end].weight && items[start].weight != 1 {
+			end++
+		}
+
+		group := make([]*prompt, end-start)
+		for i := start; i < end; i++ {
+			group[i-start] = items[i].content
+		}
+
+		result = append(result, converter.emphasize(group, items[start].weight)...)
+		start = end
+	}
+
+	return result
+}
+
+func (converter *converter) collect(contents []*prompt, weight float64) (items []weightedContent) {
+	keep := func(content *prompt) {
+		items = append(items, weightedContent{content: content, weight: 1})
+	}
+
+	for _, content := range contents {
+		switch content.kind {
+		case positiveWeight:
+			items = append(items, converter.collect(content.contents, weight*converter.sourceFactor)...)
+		case negativeWeight:
+			items = append(items, converter.collect(content.contents, weight/converter.sourceFactor)...)
+		case customWeight:
+			items = append(items, converter.collect(content.contents, weight*content.weight)...)
+		case group:
+			items = append(items, converter.collect(content.contents, weight)...)
+		case blend:
+			if converter.to == Compel {
+				for _, part := range content.contents {
+					part.contents = converter.convertContents(part.contents, weight)
+				}
+				keep(content)
+				continue
+			}
+
+			converter.unsupported("blend")
+			items = append(items, converter.collect(content.contents, weight)...)
+		case composable, conjunction:
+			if converter.to == A1111 || converter.to == Compel || converter.to == Midjourney {
+				content.contents = converter.convertContents(content.contents, weight)
+				keep(content)
+				continue
+			}
+
+			if content.kind == composable {
+				converter.unsupported("AND")
+			}
+			items = append(items, converter.collect(content.contents, weight)...)
+		case scheduled:
+			if converter.to == A1111 {
+				content.contents = converter.convertContents(content.contents, weight)
+				content.to = converter.convertContents(content.to, weight)
+				keep(content)
+				continue
+			}
+
+			converter.unsupported("prompt editing")
+			items = append(items, converter.collect(content.contents, weight)...)
+			items = append(items, converter.collect(content.to, weight)...)
+		case alternate:
+			if converter.to == A1111 {
+				for i, option := range content.options {
+					content.options[i] = converter.convertContents(option, weight)
+				}
+				keep(content)
+				continue
+			}
+
+			converter.unsupported("alternation")
+			for _, option := range content.options {
+				items = append(items, converter.collect(option, weight)...)
+			}
+		case chunkBreak:
+			if converter.to == A1111 {
+				keep(content)
+				continue
+			}
+
+			converter.unsupported("BREAK")
+		case regionBreak:
+			if converter.to == A1111 {
+				keep(content)
+				continue
+			}
+
+			converter.unsupported(content.name)
+		case lora, hypernet:
+			if converter.to == A1111 {
+				keep(content)
+				continue
+			}
+
+			// the other dialects load networks outside of the prompt
+			model := converter.parser.evaluateModel(content)
+			if content.kind == lora {
+				converter.converted.Loras = append(converter.converted.Loras, model)
+			} else {
+				converter.converted.Hypernets = append(converter.converted.Hypernets, model)
+			}
+
+			if converter.to != ComfyUI {
+				converter.unsupported(content.kind + " " + content.filename)
+			}
+		case extraNetwork:
+			if converter.to == A1111 {
+				keep(content)
+				continue
+			}
+
+			converter.unsupported(content.name + " " + content.filename)
+		case embedding:
+			switch converter.to {
+			case A1111, Compel:
+				content.prefix = ""
+			case ComfyUI:
+				content.prefix = "embedding:"
+			default:
+				converter.unsupported("embedding " + content.name)
+				continue
+			}
+
+			items = append(items, weightedContent{content: content, weight: weight})
+		case phrase:
+			if converter.to != A1111 && converter.to != ComfyUI {
+				name := phraseToString(content, func(name string) string { return name })
+				content = &prompt{kind: tag, name: name, tokens: strings.Split(name, " ")}
+			}
+
+			items = append(items, weightedContent{content: content, weight: weight})
+		case wildcard:
+			if converter.to != A1111 && converter.to != ComfyUI {
+				content = &prompt{kind: tag, name: "__" + content.name + "__", tokens: []string{"__" + content.name + "__"}}
+			}
+
+			items = append(items, weightedContent{content: content, weight: weight})
+		default:
+			items = append(items, weightedContent{content: content, weight: weight})
+		}
+	}
+
+	return items
+}
+
+func (converter *converter) emphasize(contents []*prompt, weight float64) []*prompt {
+	if weight == 1 {
+		return contents
+	}
+
+	switch converter.to {
+	case Midjourney:
+		names := make([]string, len(contents))
+		for i, content := range contents {
+			names[i] = content.name
+		}
+		converter.unsupported(fmt.Sprintf("weight %v of %q", math.Round(weight*10000)/10000, strings.Join(names, ", ")))
+
+		return contents
+	case A1111, ComfyUI, NovelAI:
+		// ((abc)) is preferred to (abc:1.21) when the weight is a power of the emphasis factor
+		for count := 1; count <= 5; count++ {
+			kind := ""
+			if math.Abs(weight-math.Pow(converter.targetFactor, float64(count))) < 1e-9 {
+				kind = positiveWeight
+			} else if math.Abs(weight-math.Pow(converter.targetFactor, float64(-count))) < 1e-9 && converter.to != ComfyUI {
+				kind = negativeWeight
+			}
+
+			if kind == "" {
+				continue
+			}
+
+			for i := 0; i < count; i++ {
+				contents = []*prompt{{kind: kind, contents: contents}}
+			}
+
+			return contents
+		}
+	}
+
+	return []*prompt{{
+		kind:     customWeight,
+		weight:   math.Round(weight*10000) / 10000,
+		contents: contents,
+	}}
+}
@@ -0,0 +1,112 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvert(t *testing.T) {
+	tests := []struct {
+		input  string
+		from   Dialect
+		to     Dialect
+		result ConvertedPrompt
+	}{
+		{
+			"((cat)), [dog], (bird:1.5)",
+			A1111,
+			NovelAI,
+			ConvertedPrompt{Prompt: "1.21::cat::, 0.9091::dog::, 1.5::bird::"},
+		},
+		{
+			"{{cat}}, [dog]",
+			NovelAI,
+			A1111,
+			ConvertedPrompt{Prompt: "(cat:1.1025), (dog:.9524)"},
+		},
+		{
+			"cat++, (red hair)-",
+			Compel,
+			A1111,
+			ConvertedPrompt{Prompt: "((cat)), (red hair:.9)"},
+		},
+		{
+			"((cat)), [dog], abc",
+			A1111,
+			Compel,
+			ConvertedPrompt{Prompt: "cat++, (dog)0.9091, abc"},
+		},
+		{
+			"(cat, dog:1.2), <lora:file:0.7>, embedding:easy, [abc:xyz:5]",
+			A1111,
+			ComfyUI,
+			ConvertedPrompt{
+				Prompt:      "(cat, dog:1.2), embedding:easy, abc, xyz",
+				Loras:       []*PromptModel{{Filename: "file", Multiplier: 0.7}},
+				Unsupported: []string{"prompt editing is not supported by comfyui"},
+			},
+		},
+		{
+			"a castle :1.2 AND (a dragon) BREAK abc",
+			A1111,
+			Midjourney,
+			ConvertedPrompt{
+				Prompt: "a castle::1.2 a dragon, abc",
+				Unsupported: []string{
+					"BREAK is not supported by midjourney",
+					`weight 1.1 of "a dragon" is not supported by midjourney`,
+				},
+			},
+		},
+		{
+			"abc ADDCOMM (xyz) ADDCOL mno",
+			A1111,
+			NovelAI,
+			ConvertedPrompt{
+				Prompt: "abc, 1.1::xyz::, mno",
+				Unsupported: []string{
+					"ADDCOMM is not supported by novelai",
+					"ADDCOL is not supported by novelai",
+				},
+			},
+		},
+		{
+			"abc ADDCOMM (xyz) ADDCOL mno",
+			A1111,
+			A1111,
+			ConvertedPrompt{Prompt: "abc ADDCOMM (xyz) ADDCOL mno"},
+		},
+		{
+			"hot:: dog::2 --ar 16:9",
+			Midjourney,
+			A1111,
+			ConvertedPrompt{
+				Prompt:      "hot AND dog :2",
+				Unsupported: []string{"parameter --ar 16:9 is not supported by a1111"},
+			},
+		},
+		{
+			`("a cat", "a dog").blend(0.7, 0.3)`,
+			Compel,
+			A1111,
+			ConvertedPrompt{
+				Prompt:      "(a cat:.7), (a dog:.3)",
+				Unsupported: []string{"blend is not supported by a1111"},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.Convert(test.input, test.from, test.to)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, *result)
+		})
+	}
+
+	_, err := parser.Convert("abc", A1111, Dialect("xyz"))
+	assert.EqualError(t, err, `unknown dialect "xyz"`)
+}
@@ -0,0 +1,41 @@
+package parser
+
+import "fmt"
+
+type Dialect string
+
+const (
+	A1111      Dialect = "a1111"
+	ComfyUI    Dialect = "comfyui"
+	NovelAI    Dialect = "novelai"
+	Compel     Dialect = "compel"
+	Midjourney Dialect = "midjourney"
+)
+
+func (dialect Dialect) isKnown() bool {
+	switch dialect {
+	case A1111, ComfyUI, NovelAI, Compel, Midjourney:
+		return true
+	default:
+		return false
+	}
+}
+
+// SetDialect switches the syntax of parsed prompts, unknown dialects are rejected.
+func (parser *PromptParser) SetDialect(dialect Dialect) error {
+	if !dialect.isKnown() {
+		return fmt.Errorf("unknown dialect %q", dialect)
+	}
+
+	parser.dialect = dialect
+	return nil
+}
+
+func (parser *PromptParser) weightMultiplier() float64 {
+	switch parser.dialect {
+	case NovelAI:
+		return 1.05
+	default:
+		return 1.1
+	}
+}
@@ -1,36 +1,198 @@
 package parser
 
-func (parser *PromptParser) evaluatePromptContents(contents []*prompt, currentWeight float64, weightMultiplier float64, evaluated *ParsedPrompt) {
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func (parser *PromptParser) scheduledContents(content *prompt, step int, totalSteps int) []*prompt {
+	// step 0 means the prompt is evaluated without a schedule, so both sides are included
+	if step == 0 {
+		return append(append([]*prompt{}, content.contents...), content.to...)
+	}
+
+	boundary := content.when
+	if boundary < 1 {
+		boundary *= float64(totalSteps)
+	}
+
+	if float64(step) <= boundary {
+		return content.contents
+	}
+
+	return content.to
+}
+
+func (parser *PromptParser) alternateContents(content *prompt, step int) []*prompt {
+	// step 0 means the prompt is evaluated without a schedule, so all options are included
+	if step == 0 {
+		contents := []*prompt{}
+		for _, option := range content.options {
+			contents = append(contents, option...)
+		}
+		return contents
+	}
+
+	return content.options[(step-1)%len(content.options)]
+}
+
+func (parser *PromptParser) evaluateModel(content *prompt) *PromptModel {
+	mutiplier := content.multiplier
+	if mutiplier == 0 {
+		mutiplier = 1
+	}
+
+	model := &PromptModel{Filename: content.filename, Multiplier: mutiplier}
+
+	// extra positional arguments are keyed by their position: <lora:file:te:unet:dyn:4>
+	args, position := map[string]string{}, 1+len(content.multipliers)
+	for _, argument := range content.arguments {
+		key, value, found := strings.Cut(argument, "=")
+		if !found {
+			position++
+			key, value = strconv.Itoa(position), argument
+		}
+		args[key] = value
+	}
+
+	if content.kind == lora {
+		parser.evaluateLoraArguments(content, model, args)
+	}
+
+	if len(args) > 0 {
+		model.Args = args
+	}
+
+	return model
+}
+
+// evaluateLoraArguments applies <lora:file:te:unet:dyn>, named te=, unet= and dyn= take precedence
+func (parser *PromptParser) evaluateLoraArguments(content *prompt, model *PromptModel, args map[string]string) {
+	if _, ok := args["dyn"]; !ok && len(content.multipliers) > 1 {
+		args["dyn"] = fmt.Sprintf("%v", content.multipliers[1])
+	}
+
+	if value, ok := args["te"]; ok {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			model.Multiplier = number
+		}
+	}
+
+	unet, hasUNet := 0.0, false
+	if value, ok := args["unet"]; ok {
+		if number, err := strconv.ParseFloat(value, 64); err == nil {
+			unet, hasUNet = number, true
+		}
+	}
+
+	if !hasUNet && len(content.multipliers) > 0 {
+		unet, hasUNet = content.multipliers[0], true
+	}
+
+	if hasUNet {
+		textEncoder := model.Multiplier
+		model.TextEncoder = &textEncoder
+		model.UNet = &unet
+	}
+}
+
+// networkMultipliers fills in the default multipliers of a registered network: <ti:file> is <ti:file:1:1>
+func (parser *PromptParser) networkMultipliers(content *prompt) []float64 {
+	network := parser.findNetwork(content.name)
+	if network == nil || len(content.multipliers) >= network.Args {
+		return content.multipliers
+	}
+
+	multipliers := append([]float64{}, content.multipliers...)
+	for len(multipliers) < network.Args {
+		multipliers = append(multipliers, network.DefaultMultiplier)
+	}
+
+	return multipliers
+}
+
+func (parser *PromptParser) evaluatePromptContents(contents []*prompt, currentWeight float64, weightMultiplier float64, step int, totalSteps int, evaluated *ParsedPrompt) {
 	for _, content := range contents {
 		switch content.kind {
 		case positiveWeight:
-			parser.evaluatePromptContents(content.contents, currentWeight*weightMultiplier, weightMultiplier, evaluated)
+			parser.evaluatePromptContents(content.contents, currentWeight*weightMultiplier, weightMultiplier, step, totalSteps, evaluated)
 		case negativeWeight:
-			parser.evaluatePromptContents(content.contents, currentWeight/weightMultiplier, weightMultiplier, evaluated)
+			parser.evaluatePromptContents(content.contents, currentWeight/weightMultiplier, weightMultiplier, step, totalSteps, evaluated)
 		case customWeight:
-			parser.evaluatePromptContents(content.contents, currentWeight*content.weight, weightMultiplier, evaluated)
-		case lora:
-			mutiplier := content.multiplier
-			if mutiplier == 0 {
-				mutiplier = 1
+			parser.evaluatePromptContents(content.contents, currentWeight*content.weight, weightMultiplier, step, totalSteps, evaluated)
+		case phrase:
+			// the text around the wildcards stays one tag: red __color__ hair
+			names := []string{}
+			for _, part := range content.contents {
+				if part.kind == wildcard {
+					evaluated.Wildcards = append(evaluated.Wildcards, &PromptWildcard{Name: part.name, Weight: currentWeight})
+				} else {
+					names = append(names, part.name)
+				}
 			}
-			evaluated.Loras = append(evaluated.Loras, &PromptModel{Filename: content.filename, Multiplier: mutiplier})
-		case hypernet:
-			mutiplier := content.multiplier
-			if mutiplier == 0 {
-				mutiplier = 1
+			if len(names) > 0 {
+				evaluated.Tags = append(evaluated.Tags, &PromptTag{Tag: strings.Join(names, " "), Weight: currentWeight, Chunk: evaluated.Breaks})
 			}
-			evaluated.Hypernets = append(evaluated.Hypernets, &PromptModel{Filename: content.filename, Multiplier: mutiplier})
+		case group, blend, conjunction:
+			parser.evaluatePromptContents(content.contents, currentWeight, weightMultiplier, step, totalSteps, evaluated)
+		case scheduled:
+			parser.evaluatePromptContents(parser.scheduledContents(content, step, totalSteps), currentWeight, weightMultiplier, step, totalSteps, evaluated)
+		case alternate:
+			parser.evaluatePromptContents(parser.alternateContents(content, step), currentWeight, weightMultiplier, step, totalSteps, evaluated)
+		case composable:
+			subPrompt := &ParsedPrompt{}
+			parser.evaluatePromptContents(content.contents, currentWeight, weightMultiplier, step, totalSteps, subPrompt)
+			evaluated.SubPrompts = append(evaluated.SubPrompts, &SubPrompt{Weight: content.weight, Prompt: subPrompt})
+			evaluated.Tags = append(evaluated.Tags, subPrompt.Tags...)
+			evaluated.Loras = append(evaluated.Loras, subPrompt.Loras...)
+			evaluated.Hypernets = append(evaluated.Hypernets, subPrompt.Hypernets...)
+			evaluated.Embeddings = append(evaluated.Embeddings, subPrompt.Embeddings...)
+			evaluated.Networks = append(evaluated.Networks, subPrompt.Networks...)
+			evaluated.Wildcards = append(evaluated.Wildcards, subPrompt.Wildcards...)
+		case embedding:
+			evaluated.Embeddings = append(evaluated.Embeddings, &PromptEmbedding{Name: content.name, Weight: currentWeight})
+		case wildcard:
+			evaluated.Wildcards = append(evaluated.Wildcards, &PromptWildcard{Name: content.name, Weight: currentWeight})
+		case chunkBreak:
+			evaluated.Breaks++
+		case regionBreak:
+			// regions are evaluated separately
+		case lora:
+			evaluated.Loras = append(evaluated.Loras, parser.evaluateModel(content))
+		case hypernet:
+			evaluated.Hypernets = append(evaluated.Hypernets, parser.evaluateModel(content))
+		case extraNetwork:
+			evaluated.Networks = append(evaluated.Networks, &PromptNetwork{
+				Kind:        content.name,
+				Filename:    content.filename,
+				Multipliers: parser.networkMultipliers(content),
+				Args:        content.arguments,
+			})
 		default:
-			evaluated.Tags = append(evaluated.Tags, &PromptTag{Tag: content.name, Weight: currentWeight})
+			evaluated.Tags = append(evaluated.Tags, &PromptTag{Tag: content.name, Weight: currentWeight, Chunk: evaluated.Breaks})
 		}
 	}
 }
 
-func (parser *PromptParser) evaluate(prompt *prompt) *ParsedPrompt {
-	currentWeight, weightMultiplier := 1.0, 1.1
-	evaluated := &ParsedPrompt{}
-	parser.evaluatePromptContents(prompt.contents, currentWeight, weightMultiplier, evaluated)
+func (parser *PromptParser) evaluate(prompt *prompt, step int, totalSteps int) *ParsedPrompt {
+	currentWeight, weightMultiplier := 1.0, parser.weightMultiplier()
+	evaluated := &ParsedPrompt{Diagnostics: prompt.diagnostics}
+	parser.evaluatePromptContents(prompt.contents, currentWeight, weightMultiplier, step, totalSteps, evaluated)
+	evaluated.Regions = parser.evaluateRegions(prompt.contents, step, totalSteps)
+
+	for _, argument := range prompt.arguments {
+		if evaluated.Parameters == nil {
+			evaluated.Parameters = map[string]string{}
+		}
+
+		// --no trees --no cars is the same as --no trees, cars
+		name, value, _ := strings.Cut(argument, " ")
+		if previous, found := evaluated.Parameters[name]; found && name == "no" && previous != "" {
+			value = previous + ", " + value
+		}
+		evaluated.Parameters[name] = value
+	}
 
 	return evaluated
 }
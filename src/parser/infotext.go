@@ -0,0 +1,227 @@
+package parser
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var infotextParameterRegex = regexp.MustCompile(`\s*(\w[\w \-/]+):\s*("(?:\\.|[^\\"])+"|[^,]*)(?:,|$)`)
+
+type GenerationParameters struct {
+	Prompt          string             `json:"prompt"`
+	NegativePrompt  string             `json:"negativePrompt"`
+	Parsed          *ParsedPromptPair  `json:"parsed"`
+	Steps           int                `json:"steps,omitempty"`
+	Sampler         string             `json:"sampler,omitempty"`
+	CFGScale        float64            `json:"cfgScale,omitempty"`
+	Seed            int64              `json:"seed,omitempty"`
+	Width           int                `json:"width,omitempty"`
+	Height          int                `json:"height,omitempty"`
+	Model           string             `json:"model,omitempty"`
+	ModelHash       string             `json:"modelHash,omitempty"`
+	LoraHashes      map[string]string  `json:"loraHashes,omitempty"`
+	EmbeddingHashes map[string]string  `json:"embeddingHashes,omitempty"`
+	Extras          map[string]string  `json:"extras,omitempty"`
+	Characters      []*CharacterPrompt `json:"characters,omitempty"`
+}
+
+// ParseInfotext parses A1111 generation info: prompt, "Negative prompt:" and the parameter line.
+func (parser *PromptParser) ParseInfotext(input string) (*GenerationParameters, error) {
+	text, parameterLine := splitInfotext(input)
+	positive, negative := SplitPromptPair(text)
+
+	// A1111 treats line breaks inside prompts as plain whitespace
+	parsed, err := parser.ParsePromptPair(strings.ReplaceAll(positive, "\n", " "), strings.ReplaceAll(negative, "\n", " "))
+	if err != nil {
+		return &GenerationParameters{}, err
+	}
+
+	parameters := &GenerationParameters{
+		Prompt:         positive,
+		NegativePrompt: negative,
+		Parsed:         parsed,
+	}
+
+	for _, match := range infotextParameterRegex.FindAllStringSubmatch(parameterLine, -1) {
+		key := strings.TrimSpace(match[1])
+		value, err := unquoteInfotextValue(strings.TrimSpace(match[2]))
+		if err != nil {
+			return &GenerationParameters{}, fmt.Errorf("invalid %q value: %w", key, err)
+		}
+
+		err = parameters.set(key, value)
+		if err != nil {
+			return &GenerationParameters{}, err
+		}
+	}
+
+	return parameters, nil
+}
+
+// The last line holds parameters only when it contains at least three "key: value" pairs.
+func splitInfotext(input string) (string, string) {
+	input = strings.TrimSpace(strings.ReplaceAll(input, "\r\n", "\n"))
+
+	index := strings.LastIndex(input, "\n")
+	lastLine := input[index+1:]
+	if len(infotextParameterRegex.FindAllString(lastLine, -1)) < 3 {
+		return input, ""
+	}
+
+	if index < 0 {
+		return "", lastLine
+	}
+
+	return strings.TrimSpace(input[:index]), lastLine
+}
+
+func unquoteInfotextValue(value string) (string, error) {
+	if len(value) < 2 || !strings.HasPrefix(value, `"`) || !strings.HasSuffix(value, `"`) {
+		return value, nil
+	}
+
+	var unquoted string
+	err := json.Unmarshal([]byte(value), &unquoted)
+
+	return unquoted, err
+}
+
+func (parameters *GenerationParameters) set(key string, value string) error {
+	var err error
+
+	switch key {
+	case "Steps":
+		parameters.Steps, err = strconv.Atoi(value)
+	case "Sampler":
+		parameters.Sampler = value
+	case "CFG scale":
+		parameters.CFGScale, err = strconv.ParseFloat(value, 64)
+	case "Seed":
+		parameters.Seed, err = strconv.ParseInt(value, 10, 64)
+	case "Size":
+		width, height, found := strings.Cut(value, "x")
+		if !found {
+			return fmt.Errorf("invalid %q value %q", key, value)
+		}
+
+		parameters.Width, err = strconv.Atoi(width)
+		if err == nil {
+			parameters.Height, err = strconv.Atoi(height)
+		}
+	case "Model":
+		parameters.Model = value
+	case "Model hash":
+		parameters.ModelHash = value
+	case "Lora hashes":
+		parameters.LoraHashes = parseInfotextHashes(value)
+	case "TI hashes":
+		parameters.EmbeddingHashes = parseInfotextHashes(value)
+	default:
+		parameters.setExtra(key, value)
+	}
+
+	if err != nil {
+		return fmt.Errorf("invalid %q value %q", key, value)
+	}
+
+	return nil
+}
+
+func (parameters *GenerationParameters) setExtra(key string, value string) {
+	if parameters.Extras == nil {
+		parameters.Extras = make(map[string]string)
+	}
+	parameters.Extras[key] = value
+}
+
+// "name: hash, name: hash"
+func parseInfotextHashes(value string) map[string]string {
+	hashes := make(map[string]string)
+	for _, item := range strings.Split(value, ",") {
+		name, hash, found := strings.Cut(item, ":")
+		if found {
+			hashes[strings.TrimSpace(name)] = strings.TrimSpace(hash)
+		}
+	}
+
+	return hashes
+}
+
+// Infotext serializes the parameters back into A1111 generation info.
+func (parameters *GenerationParameters) Infotext() string {
+	values := make([]string, 0)
+	add := func(key string, value string) {
+		if len(value) > 0 {
+			values = append(values, key+": "+quoteInfotextValue(value))
+		}
+	}
+
+	if parameters.Steps != 0 {
+		add("Steps", strconv.Itoa(parameters.Steps))
+	}
+	add("Sampler", parameters.Sampler)
+	if parameters.CFGScale != 0 {
+		add("CFG scale", strconv.FormatFloat(parameters.CFGScale, 'f', -1, 64))
+	}
+	if parameters.Seed != 0 {
+		add("Seed", strconv.FormatInt(parameters.Seed, 10))
+	}
+	if parameters.Width != 0 || parameters.Height != 0 {
+		add("Size", fmt.Sprintf("%dx%d", parameters.Width, parameters.Height))
+	}
+	add("Model hash", parameters.ModelHash)
+	add("Model", parameters.Model)
+	add("Lora hashes", infotextHashesToString(parameters.LoraHashes))
+	add("TI hashes", infotextHashesToString(parameters.EmbeddingHashes))
+	for _, key := range sortedKeys(parameters.Extras) {
+		add(key, parameters.Extras[key])
+	}
+
+	lines := []string{parameters.Prompt}
+	if len(parameters.NegativePrompt) > 0 {
+		lines = append(lines, negativePromptPrefix+" "+parameters.NegativePrompt)
+	}
+	if len(values) > 0 {
+		lines = append(lines, strings.Join(values, ", "))
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// Values with commas, colons or line breaks are written as JSON strings, same as A1111.
+func quoteInfotextValue(value string) string {
+	if !strings.ContainsAny(value, ",:\n") {
+		return value
+	}
+
+	buffer := &bytes.Buffer{}
+	encoder := json.NewEncoder(buffer)
+	encoder.SetEscapeHTML(false)
+	encoder.Encode(value)
+
+	return strings.TrimRight(buffer.String(), "\n")
+}
+
+func infotextHashesToString(hashes map[string]string) string {
+	items := make([]string, 0, len(hashes))
+	for _, name := range sortedKeys(hashes) {
+		items = append(items, name+": "+hashes[name])
+	}
+
+	return strings.Join(items, ", ")
+}
+
+func sortedKeys(values map[string]string) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
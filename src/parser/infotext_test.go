@@ -0,0 +1,148 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseInfotext(t *testing.T) {
+	parser := NewPromptParser()
+
+	input := "masterpiece, (cat), <lora:style:0.8>\n" +
+		"Negative prompt: [blurry],\nlowres\n" +
+		`Steps: 20, Sampler: DPM++ 2M, CFG scale: 7, Seed: 123, Size: 512x768, Model hash: abc123, Model: sdxl, ` +
+		`Lora hashes: "style: d41d8, other: e52e9", TI hashes: "EasyNegative: f00", Hires prompt: "cat, \"dog\"", Version: v1.7.0`
+
+	result, err := parser.ParseInfotext(input)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &GenerationParameters{
+		Prompt:         "masterpiece, (cat), <lora:style:0.8>",
+		NegativePrompt: "[blurry],\nlowres",
+		Parsed: &ParsedPromptPair{
+			Positive: &ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "masterpiece", Weight: 1},
+					{Tag: "cat", Weight: 1.1},
+				},
+				Loras: []*PromptModel{{Filename: "style", Multiplier: 0.8}},
+			},
+			Negative: &ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "blurry", Weight: 0.9090909090909091},
+					{Tag: "lowres", Weight: 1},
+				},
+			},
+		},
+		Steps:           20,
+		Sampler:         "DPM++ 2M",
+		CFGScale:        7,
+		Seed:            123,
+		Width:           512,
+		Height:          768,
+		Model:           "sdxl",
+		ModelHash:       "abc123",
+		LoraHashes:      map[string]string{"style": "d41d8", "other": "e52e9"},
+		EmbeddingHashes: map[string]string{"EasyNegative": "f00"},
+		Extras: map[string]string{
+			"Hires prompt": `cat, "dog"`,
+			"Version":      "v1.7.0",
+		},
+	}, result)
+}
+
+func TestParseInfotextWithoutParameters(t *testing.T) {
+	parser := NewPromptParser()
+
+	tests := []struct {
+		input    string
+		positive string
+		negative string
+		steps    int
+	}{
+		{"cat, dog", "cat, dog", "", 0},
+		{"cat\nNegative prompt: dog", "cat", "dog", 0},
+		{"Steps: 30, Sampler: Euler a, Seed: 1", "", "", 30},
+		{"cat\nSteps: 30, Sampler: Euler a", "cat\nSteps: 30, Sampler: Euler a", "", 0},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParseInfotext(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.positive, result.Prompt)
+			assert.Equal(t, test.negative, result.NegativePrompt)
+			assert.Equal(t, test.steps, result.Steps)
+		})
+	}
+}
+
+func TestParseInfotextErrors(t *testing.T) {
+	parser := NewPromptParser()
+
+	tests := []struct {
+		input string
+		err   string
+	}{
+		{"cat\nSteps: many, Sampler: Euler a, Seed: 1", `invalid "Steps" value "many"`},
+		{"cat\nSteps: 20, Size: 512, Seed: 1", `invalid "Size" value "512"`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			_, err := parser.ParseInfotext(test.input)
+			assert.EqualError(t, err, test.err)
+		})
+	}
+}
+
+func TestInfotext(t *testing.T) {
+	parameters := &GenerationParameters{
+		Prompt:         "masterpiece, (cat)",
+		NegativePrompt: "[blurry]",
+		Steps:          20,
+		Sampler:        "DPM++ 2M",
+		CFGScale:       7.5,
+		Seed:           123,
+		Width:          512,
+		Height:         768,
+		ModelHash:      "abc123",
+		Model:          "sdxl",
+		LoraHashes:     map[string]string{"style": "d41d8", "other": "e52e9"},
+		Extras: map[string]string{
+			"Version":      "v1.7.0",
+			"Hires prompt": "cat, \"dog\" <lora:x:1>",
+		},
+	}
+
+	assert.Equal(t, "masterpiece, (cat)\n"+
+		"Negative prompt: [blurry]\n"+
+		`Steps: 20, Sampler: DPM++ 2M, CFG scale: 7.5, Seed: 123, Size: 512x768, Model hash: abc123, Model: sdxl, `+
+		`Lora hashes: "other: e52e9, style: d41d8", Hires prompt: "cat, \"dog\" <lora:x:1>", Version: v1.7.0`,
+		parameters.Infotext())
+	assert.Equal(t, "cat", (&GenerationParameters{Prompt: "cat"}).Infotext())
+}
+
+func TestInfotextRoundTrip(t *testing.T) {
+	parser := NewPromptParser()
+
+	tests := []string{
+		"cat",
+		"cat, (dog:1.2)\nNegative prompt: [blurry], <lora:bad:0.5>",
+		"cat\nSteps: 20, Sampler: Euler a, CFG scale: 7, Seed: 1, Size: 512x512",
+		"cat,\ndog\nNegative prompt: lowres,\nbad hands\n" +
+			`Steps: 30, Sampler: DPM++ 2M, Seed: 42, Model: sd_xl, TI hashes: "EasyNegative: f00", Hires upscaler: "4x: Ultra, Sharp", Note: "line\nbreak"`,
+	}
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			parameters, err := parser.ParseInfotext(test)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test, parameters.Infotext())
+
+			reparsed, err := parser.ParseInfotext(parameters.Infotext())
+			assert.Equal(t, nil, err)
+			assert.Equal(t, parameters, reparsed)
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+func (parser *PromptParser) parseMidjourneyTags(text string) (contents []*prompt) {
+	for _, part := range strings.Split(text, ",") {
+		tokens := strings.Fields(part)
+		if len(tokens) == 0 {
+			continue
+		}
+
+		contents = append(contents, &prompt{
+			kind:   tag,
+			name:   strings.Join(tokens, " "),
+			tokens: tokens,
+		})
+	}
+
+	return contents
+}
+
+func (parser *PromptParser) parseMidjourney(input string) (*prompt, error) {
+	result := &prompt{}
+
+	// --ar 16:9 --no trees, cars --tile
+	parts := regexp.MustCompile(`(?:^|\s)--`).Split(input, -1)
+	input = parts[0]
+	for _, part := range parts[1:] {
+		name, value, _ := strings.Cut(strings.TrimSpace(part), " ")
+		if name == "" {
+			continue
+		}
+
+		result.arguments = append(result.arguments, strings.TrimSpace(name+" "+strings.TrimSpace(value)))
+	}
+
+	// hot:: dog::2 splits the prompt into weighted segments
+	matches := regexp.MustCompile(`::(-?\d*\.?\d+)?`).FindAllStringSubmatchIndex(input, -1)
+	if len(matches) == 0 {
+		result.contents = parser.parseMidjourneyTags(input)
+		return result, nil
+	}
+
+	start := 0
+	for _, match := range matches {
+		weight := 1.0
+		if match[2] >= 0 {
+			weight, _ = strconv.ParseFloat(input[match[2]:match[3]], 64)
+		}
+
+		result.contents = append(result.contents, &prompt{
+			kind:     composable,
+			weight:   weight,
+			contents: parser.parseMidjourneyTags(input[start:match[0]]),
+		})
+		start = match[1]
+	}
+
+	if rest := parser.parseMidjourneyTags(input[start:]); len(rest) > 0 {
+		result.contents = append(result.contents, &prompt{
+			kind:     composable,
+			weight:   1,
+			contents: rest,
+		})
+	}
+
+	return result, nil
+}
+
+func (parser *PromptParser) midjourneyTagsToString(contents []*prompt) string {
+	names := make([]string, len(contents))
+	for i, content := range contents {
+		names[i] = content.name
+	}
+
+	return strings.Join(names, ", ")
+}
+
+func (parser *PromptParser) midjourneyToString(prompt *prompt) string {
+	segments := []string{}
+	if len(prompt.contents) > 0 && prompt.contents[0].kind == composable {
+		for i, content := range prompt.contents {
+			segment := parser.midjourneyTagsToString(content.contents)
+			if content.weight != 1 {
+				segment += fmt.Sprintf("::%v", content.weight)
+			} else if i < len(prompt.contents)-1 {
+				segment += "::"
+			}
+			segments = append(segments, segment)
+		}
+	} else {
+		segments = append(segments, parser.midjourneyTagsToString(prompt.contents))
+	}
+
+	for _, argument := range prompt.arguments {
+		segments = append(segments, "--"+argument)
+	}
+
+	return strings.Join(segments, " ")
+}
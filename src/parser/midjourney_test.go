@@ -0,0 +1,94 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseMidjourneyPrompt(t *testing.T) {
+	tests := []struct {
+		input  string
+		result ParsedPrompt
+	}{
+		{
+			"red  cat, (sitting:1.2) --ar 16:9 --no trees, cars --tile",
+			ParsedPrompt{
+				Tags:       []*PromptTag{{Tag: "red cat", Weight: 1}, {Tag: "(sitting:1.2)", Weight: 1}},
+				Parameters: map[string]string{"ar": "16:9", "no": "trees, cars", "tile": ""},
+			},
+		},
+		{
+			"hot:: dog::2",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "hot", Weight: 1}, {Tag: "dog", Weight: 1}},
+				SubPrompts: []*SubPrompt{
+					{Weight: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "hot", Weight: 1}}}},
+					{Weight: 2, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "dog", Weight: 1}}}},
+				},
+			},
+		},
+		{
+			"cat --no trees --ar 1:1 --no cars",
+			ParsedPrompt{
+				Tags:       []*PromptTag{{Tag: "cat", Weight: 1}},
+				Parameters: map[string]string{"ar": "1:1", "no": "trees, cars"},
+			},
+		},
+		{
+			"hot:: 2 dogs",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "hot", Weight: 1}, {Tag: "2 dogs", Weight: 1}},
+				SubPrompts: []*SubPrompt{
+					{Weight: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "hot", Weight: 1}}}},
+					{Weight: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "2 dogs", Weight: 1}}}},
+				},
+			},
+		},
+		{
+			"still life::1 fruit::-.5 --v 6",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "still life", Weight: 1}, {Tag: "fruit", Weight: 1}},
+				SubPrompts: []*SubPrompt{
+					{Weight: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "still life", Weight: 1}}}},
+					{Weight: -0.5, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "fruit", Weight: 1}}}},
+				},
+				Parameters: map[string]string{"v": "6"},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+	parser.SetDialect(Midjourney)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParsePrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, *result)
+		})
+	}
+}
+
+func TestMidjourneyPromptToString(t *testing.T) {
+	tests := []struct {
+		input  string
+		result string
+	}{
+		{"red  cat,,  blue sky  --ar  16:9 --v 6", "red cat, blue sky --ar 16:9 --v 6"},
+		{"hot ::dog::2", "hot:: dog::2"},
+		{"hot:: 2 dogs", "hot:: 2 dogs"},
+		{"space ship::3 --stylize 250", "space ship::3 --stylize 250"},
+	}
+
+	parser := NewPromptParser()
+	parser.SetDialect(Midjourney)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.BeautifyPrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
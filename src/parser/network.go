@@ -0,0 +1,31 @@
+package parser
+
+import "slices"
+
+type NetworkKind struct {
+	Name              string
+	Aliases           []string
+	DefaultMultiplier float64
+	Args              int
+}
+
+func (parser *PromptParser) RegisterNetwork(network *NetworkKind) {
+	for i, registered := range parser.networks {
+		if registered.Name == network.Name {
+			parser.networks[i] = network
+			return
+		}
+	}
+
+	parser.networks = append(parser.networks, network)
+}
+
+func (parser *PromptParser) findNetwork(name string) *NetworkKind {
+	for _, network := range parser.networks {
+		if network.Name == name || slices.Contains(network.Aliases, name) {
+			return network
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"strings"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type Diagnostic struct {
+	Offset      int    `json:"offset"`
+	Original    string `json:"original"`
+	Replacement string `json:"replacement"`
+	Reason      string `json:"reason"`
+}
+
+const (
+	reasonComposition = "unicode normalization"
+	reasonFullWidth   = "full-width punctuation"
+	reasonQuote       = "smart quote"
+	reasonSpace       = "non-breaking space"
+	reasonZeroWidth   = "zero-width character"
+)
+
+type replacement struct {
+	value  string
+	reason string
+}
+
+// Characters of CJK input methods and word processors replaced with their prompt syntax
+// equivalents. Zero-width joiners are kept, emoji sequences and some scripts need them.
+var replacements = map[rune]replacement{
+	'（':      {"(", reasonFullWidth},
+	'）':      {")", reasonFullWidth},
+	'［':      {"[", reasonFullWidth},
+	'］':      {"]", reasonFullWidth},
+	'【':      {"[", reasonFullWidth},
+	'】':      {"]", reasonFullWidth},
+	'｛':      {"{", reasonFullWidth},
+	'｝':      {"}", reasonFullWidth},
+	'＜':      {"<", reasonFullWidth},
+	'＞':      {">", reasonFullWidth},
+	'：':      {":", reasonFullWidth},
+	'，':      {",", reasonFullWidth},
+	'、':      {",", reasonFullWidth},
+	'｜':      {"|", reasonFullWidth},
+	'‘':      {"'", reasonQuote},
+	'’':      {"'", reasonQuote},
+	'“':      {"\"", reasonQuote},
+	'”':      {"\"", reasonQuote},
+	'\u00a0': {" ", reasonSpace},
+	'\u2007': {" ", reasonSpace},
+	'\u202f': {" ", reasonSpace},
+	'\u3000': {" ", reasonSpace},
+	'\u200b': {"", reasonZeroWidth},
+	'\u2060': {"", reasonZeroWidth},
+	'\ufeff': {"", reasonZeroWidth},
+}
+
+// SetNormalization enables Normalize before tokenizing, changes are reported in Diagnostics.
+func (parser *PromptParser) SetNormalization(enabled bool) {
+	parser.normalize = enabled
+}
+
+// Normalize composes characters (NFC) and replaces full-width punctuation, smart quotes,
+// non-breaking and zero-width spaces. Diagnostic offsets are byte offsets in the input.
+func Normalize(input string) (string, []*Diagnostic) {
+	var diagnostics []*Diagnostic
+	var result strings.Builder
+
+	var iter norm.Iter
+	iter.InitString(norm.NFC, input)
+	for !iter.Done() {
+		start := iter.Pos()
+		segment := string(iter.Next())
+		original := input[start:iter.Pos()]
+
+		if segment != original {
+			diagnostics = append(diagnostics, &Diagnostic{Offset: start, Original: original, Replacement: segment, Reason: reasonComposition})
+		}
+
+		for offset, char := range segment {
+			replaced, found := replacements[char]
+			if !found {
+				result.WriteRune(char)
+				continue
+			}
+
+			// offsets inside composed segments point to the segment
+			if segment != original {
+				offset = 0
+			}
+
+			diagnostics = append(diagnostics, &Diagnostic{
+				Offset:      start + offset,
+				Original:    string(char),
+				Replacement: replaced.value,
+				Reason:      replaced.reason,
+			})
+			result.WriteString(replaced.value)
+		}
+	}
+
+	return result.String(), diagnostics
+}
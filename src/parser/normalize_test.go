@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		input       string
+		result      string
+		diagnostics []*Diagnostic
+	}{
+		{"abc, (xyz:1.2)", "abc, (xyz:1.2)", nil},
+		{
+			"（猫：1.2），【犬】",
+			"(猫:1.2),[犬]",
+			[]*Diagnostic{
+				{Offset: 0, Original: "（", Replacement: "(", Reason: reasonFullWidth},
+				{Offset: 6, Original: "：", Replacement: ":", Reason: reasonFullWidth},
+				{Offset: 12, Original: "）", Replacement: ")", Reason: reasonFullWidth},
+				{Offset: 15, Original: "，", Replacement: ",", Reason: reasonFullWidth},
+				{Offset: 18, Original: "【", Replacement: "[", Reason: reasonFullWidth},
+				{Offset: 24, Original: "】", Replacement: "]", Reason: reasonFullWidth},
+			},
+		},
+		{
+			"“red”\u00a0hair\u200b",
+			"\"red\" hair",
+			[]*Diagnostic{
+				{Offset: 0, Original: "“", Replacement: "\"", Reason: reasonQuote},
+				{Offset: 6, Original: "”", Replacement: "\"", Reason: reasonQuote},
+				{Offset: 9, Original: "\u00a0", Replacement: " ", Reason: reasonSpace},
+				{Offset: 15, Original: "\u200b", Replacement: "", Reason: reasonZeroWidth},
+			},
+		},
+		{
+			"cafe\u0301, 👨\u200d👩\u200d👧",
+			"café, 👨\u200d👩\u200d👧",
+			[]*Diagnostic{
+				{Offset: 3, Original: "e\u0301", Replacement: "é", Reason: reasonComposition},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, diagnostics := Normalize(test.input)
+			assert.Equal(t, test.result, result)
+			assert.Equal(t, test.diagnostics, diagnostics)
+		})
+	}
+}
+
+func TestParsePromptNormalization(t *testing.T) {
+	parser := NewPromptParser()
+
+	result, err := parser.ParsePrompt("（猫：1.2），blue\u3000eyes")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []*PromptTag{{Tag: "（猫：1.2），blue\u3000eyes", Weight: 1}}, result.Tags)
+
+	parser.SetNormalization(true)
+
+	result, err = parser.ParsePrompt("（猫：1.2），blue\u3000eyes")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &ParsedPrompt{
+		Tags: []*PromptTag{
+			{Tag: "猫", Weight: 1.2},
+			{Tag: "blue eyes", Weight: 1},
+		},
+		Diagnostics: []*Diagnostic{
+			{Offset: 0, Original: "（", Replacement: "(", Reason: reasonFullWidth},
+			{Offset: 6, Original: "：", Replacement: ":", Reason: reasonFullWidth},
+			{Offset: 12, Original: "）", Replacement: ")", Reason: reasonFullWidth},
+			{Offset: 15, Original: "，", Replacement: ",", Reason: reasonFullWidth},
+			{Offset: 22, Original: "\u3000", Replacement: " ", Reason: reasonSpace},
+		},
+	}, result)
+
+	beautified, err := parser.BeautifyPrompt("（猫：1.2），blue\u3000eyes")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "(猫:1.2), blue eyes", beautified)
+}
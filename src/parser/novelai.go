@@ -0,0 +1,159 @@
+package parser
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/junte/stable-diffusion-prompt-parser/src/reader"
+)
+
+func (parser *PromptParser) isNovelAIWeight(reader *reader.TokenReader) bool {
+	tokens, err := reader.GetMultipleTokens(2)
+	if err != nil || tokens[1] != "::" {
+		return false
+	}
+
+	_, err = strconv.ParseFloat(tokens[0], 64)
+	return err == nil
+}
+
+func (parser *PromptParser) parseNovelAITagPrompt(reader *reader.TokenReader) *prompt {
+	tokens := []string{}
+	invalidTokens := []string{"{", "}", "[", "]", ",", "|", "::", ""}
+	for {
+		token := reader.GetToken()
+
+		if slices.Contains(invalidTokens, token) || parser.isNovelAIWeight(reader) {
+			break
+		}
+
+		tokens = append(tokens, parser.escapeToken(token))
+		reader.NextToken()
+	}
+
+	return &prompt{
+		kind:   tag,
+		name:   strings.Join(tokens, " "),
+		tokens: tokens,
+	}
+}
+
+func (parser *PromptParser) parseNovelAIBracketPrompt(reader *reader.TokenReader, kind string, closing string) (*prompt, error) {
+	reader.NextToken()
+	contents, err := parser.parseNovelAIContents(reader)
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	// RECOVER: missing } or ]
+	if reader.GetToken() == closing {
+		reader.NextToken()
+	}
+
+	return &prompt{
+		kind:     kind,
+		contents: contents,
+	}, nil
+}
+
+func (parser *PromptParser) parseNovelAIWeightPrompt(reader *reader.TokenReader) (*prompt, error) {
+	weight, err := strconv.ParseFloat(reader.GetToken(), 64)
+	if err != nil {
+		return &prompt{}, fmt.Errorf("%v", err)
+	}
+
+	reader.NextToken()
+	reader.NextToken()
+	contents, err := parser.parseNovelAIContents(reader)
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	// RECOVER: 1.5::abc (missing closing ::)
+	if reader.GetToken() == "::" {
+		reader.NextToken()
+	}
+
+	return &prompt{
+		kind:     customWeight,
+		weight:   weight,
+		contents: contents,
+	}, nil
+}
+
+func (parser *PromptParser) parseNovelAIContents(reader *reader.TokenReader) (contents []*prompt, err error) {
+	for {
+		var content *prompt
+
+		switch reader.GetToken() {
+		case ",", "|":
+			reader.NextToken()
+			continue
+		case "}", "]", "::", "":
+			return contents, nil
+		case "{":
+			content, err = parser.parseNovelAIBracketPrompt(reader, positiveWeight, "}")
+		case "[":
+			content, err = parser.parseNovelAIBracketPrompt(reader, negativeWeight, "]")
+		default:
+			if parser.isNovelAIWeight(reader) {
+				content, err = parser.parseNovelAIWeightPrompt(reader)
+			} else {
+				content = parser.parseNovelAITagPrompt(reader)
+			}
+		}
+
+		if err != nil {
+			return nil, err
+		}
+
+		contents = append(contents, content)
+	}
+}
+
+func (parser *PromptParser) parseNovelAI(input string) (*prompt, error) {
+	prompt := &prompt{}
+	reader := reader.NewNovelAITokenReader(input)
+
+	for {
+		switch reader.GetToken() {
+		case "}", "]", "::":
+			reader.NextToken()
+			continue
+		case "":
+			return prompt, nil
+		default:
+			contents, err := parser.parseNovelAIContents(reader)
+			if err != nil {
+				return prompt, err
+			}
+
+			prompt.contents = append(prompt.contents, contents...)
+		}
+	}
+}
+
+func escapeNovelAIName(name string) string {
+	return regexp.MustCompile(`([\\{}\[\],|])`).ReplaceAllString(name, `\$1`)
+}
+
+func (parser *PromptParser) novelAIContentsToString(contents []*prompt) string {
+	results := make([]string, len(contents))
+	for i, content := range contents {
+		switch content.kind {
+		case positiveWeight:
+			results[i] = "{" + parser.novelAIContentsToString(content.contents) + "}"
+		case negativeWeight:
+			results[i] = "[" + parser.novelAIContentsToString(content.contents) + "]"
+		case customWeight:
+			results[i] = fmt.Sprintf("%v::", content.weight) + parser.novelAIContentsToString(content.contents) + "::"
+		default:
+			results[i] = escapeNovelAIName(content.name)
+		}
+	}
+
+	return strings.Join(results, ", ")
+}
@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type CharacterPrompt struct {
+	Prompt         string            `json:"prompt"`
+	NegativePrompt string            `json:"negativePrompt"`
+	Parsed         *ParsedPromptPair `json:"parsed"`
+}
+
+type novelAICaption struct {
+	Caption struct {
+		BaseCaption  string `json:"base_caption"`
+		CharCaptions []struct {
+			CharCaption string `json:"char_caption"`
+		} `json:"char_captions"`
+	} `json:"caption"`
+}
+
+type novelAIComment struct {
+	Prompt           string          `json:"prompt"`
+	UC               string          `json:"uc"`
+	Steps            int             `json:"steps"`
+	Scale            float64         `json:"scale"`
+	Seed             int64           `json:"seed"`
+	Sampler          string          `json:"sampler"`
+	Width            int             `json:"width"`
+	Height           int             `json:"height"`
+	V4Prompt         *novelAICaption `json:"v4_prompt"`
+	V4NegativePrompt *novelAICaption `json:"v4_negative_prompt"`
+}
+
+// Comment keys stored in typed fields or prompts
+var novelAICommentKeys = []string{"prompt", "uc", "steps", "scale", "seed", "sampler", "width", "height", "v4_prompt", "v4_negative_prompt"}
+
+// ParseNovelAIMetadata parses "Description", "Comment" and "Source" image text chunks of NovelAI images,
+// prompts are parsed with NovelAI emphasis.
+func (parser *PromptParser) ParseNovelAIMetadata(chunks map[string]string) (*GenerationParameters, error) {
+	data, found := chunks["Comment"]
+	if !found {
+		return &GenerationParameters{}, errors.New("no NovelAI comment found")
+	}
+
+	var comment novelAIComment
+	if err := json.Unmarshal([]byte(data), &comment); err != nil {
+		return &GenerationParameters{}, fmt.Errorf("invalid NovelAI comment: %w", err)
+	}
+
+	var extras map[string]any
+	if err := json.Unmarshal([]byte(data), &extras); err != nil {
+		return &GenerationParameters{}, fmt.Errorf("invalid NovelAI comment: %w", err)
+	}
+
+	novelAI := *parser
+	novelAI.dialect = NovelAI
+
+	parameters := &GenerationParameters{
+		Prompt:         firstNonEmpty(chunks["Description"], comment.Prompt, comment.V4Prompt.baseCaption()),
+		NegativePrompt: firstNonEmpty(comment.UC, comment.V4NegativePrompt.baseCaption()),
+		Steps:          comment.Steps,
+		Sampler:        comment.Sampler,
+		CFGScale:       comment.Scale,
+		Seed:           comment.Seed,
+		Width:          comment.Width,
+		Height:         comment.Height,
+		Model:          chunks["Source"],
+	}
+
+	parsed, err := novelAI.ParsePromptPair(parameters.Prompt, parameters.NegativePrompt)
+	if err != nil {
+		return &GenerationParameters{}, err
+	}
+	parameters.Parsed = parsed
+
+	positives, negatives := comment.V4Prompt.charCaptions(), comment.V4NegativePrompt.charCaptions()
+	for i, positive := range positives {
+		character := &CharacterPrompt{Prompt: positive}
+		if i < len(negatives) {
+			character.NegativePrompt = negatives[i]
+		}
+
+		character.Parsed, err = novelAI.ParsePromptPair(character.Prompt, character.NegativePrompt)
+		if err != nil {
+			return &GenerationParameters{}, err
+		}
+
+		parameters.Characters = append(parameters.Characters, character)
+	}
+
+	for key, value := range extras {
+		if slices.Contains(novelAICommentKeys, key) {
+			continue
+		}
+
+		// nested values like reference images are skipped
+		switch value := value.(type) {
+		case string:
+			parameters.setExtra(key, value)
+		case float64:
+			parameters.setExtra(key, strconv.FormatFloat(value, 'f', -1, 64))
+		case bool:
+			parameters.setExtra(key, strconv.FormatBool(value))
+		}
+	}
+
+	return parameters, nil
+}
+
+func (caption *novelAICaption) baseCaption() string {
+	if caption == nil {
+		return ""
+	}
+
+	return caption.Caption.BaseCaption
+}
+
+func (caption *novelAICaption) charCaptions() []string {
+	captions := make([]string, 0)
+	if caption == nil {
+		return captions
+	}
+
+	for _, character := range caption.Caption.CharCaptions {
+		captions = append(captions, character.CharCaption)
+	}
+
+	return captions
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if len(strings.TrimSpace(value)) > 0 {
+			return value
+		}
+	}
+
+	return ""
+}
@@ -0,0 +1,104 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNovelAIMetadata(t *testing.T) {
+	parser := NewPromptParser()
+
+	comment := `{"prompt": "ignored", "steps": 28, "height": 1216, "width": 832, "scale": 5.5, "seed": 42,
+		"sampler": "k_euler_ancestral", "noise_schedule": "karras", "cfg_rescale": 0, "sm": false,
+		"uc": "[lowres], bad anatomy", "reference_image_multiple": [],
+		"v4_prompt": {"caption": {"base_caption": "ignored", "char_captions": [
+			{"char_caption": "{girl}, red hair", "centers": [{"x": 0.5, "y": 0.5}]},
+			{"char_caption": "boy", "centers": [{"x": 0.1, "y": 0.1}]}]}},
+		"v4_negative_prompt": {"caption": {"base_caption": "ignored", "char_captions": [{"char_caption": "blue hair"}]}}}`
+
+	result, err := parser.ParseNovelAIMetadata(map[string]string{
+		"Description": "{{cat}}, 1.5::garden::",
+		"Comment":     comment,
+		"Source":      "NovelAI Diffusion V4 F6E18726",
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, &GenerationParameters{
+		Prompt:         "{{cat}}, 1.5::garden::",
+		NegativePrompt: "[lowres], bad anatomy",
+		Parsed: &ParsedPromptPair{
+			Positive: &ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "cat", Weight: 1.1025},
+					{Tag: "garden", Weight: 1.5},
+				},
+			},
+			Negative: &ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "lowres", Weight: 0.9523809523809523},
+					{Tag: "bad anatomy", Weight: 1},
+				},
+			},
+		},
+		Steps:    28,
+		Sampler:  "k_euler_ancestral",
+		CFGScale: 5.5,
+		Seed:     42,
+		Width:    832,
+		Height:   1216,
+		Model:    "NovelAI Diffusion V4 F6E18726",
+		Extras: map[string]string{
+			"noise_schedule": "karras",
+			"cfg_rescale":    "0",
+			"sm":             "false",
+		},
+		Characters: []*CharacterPrompt{
+			{
+				Prompt:         "{girl}, red hair",
+				NegativePrompt: "blue hair",
+				Parsed: &ParsedPromptPair{
+					Positive: &ParsedPrompt{Tags: []*PromptTag{{Tag: "girl", Weight: 1.05}, {Tag: "red hair", Weight: 1}}},
+					Negative: &ParsedPrompt{Tags: []*PromptTag{{Tag: "blue hair", Weight: 1}}},
+				},
+			},
+			{
+				Prompt: "boy",
+				Parsed: &ParsedPromptPair{
+					Positive: &ParsedPrompt{Tags: []*PromptTag{{Tag: "boy", Weight: 1}}},
+					Negative: &ParsedPrompt{},
+				},
+			},
+		},
+	}, result)
+
+	// the parser itself keeps its dialect
+	parsed, err := parser.ParsePrompt("{cat}")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []*PromptTag{{Tag: "{cat}", Weight: 1}}, parsed.Tags)
+}
+
+func TestParseNovelAIMetadataFallbacks(t *testing.T) {
+	parser := NewPromptParser()
+
+	result, err := parser.ParseNovelAIMetadata(map[string]string{
+		"Comment": `{"v4_prompt": {"caption": {"base_caption": "cat"}}, "v4_negative_prompt": {"caption": {"base_caption": "dog"}}}`,
+	})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "cat", result.Prompt)
+	assert.Equal(t, "dog", result.NegativePrompt)
+
+	result, err = parser.ParseNovelAIMetadata(map[string]string{"Comment": `{"prompt": "cat", "uc": "dog"}`})
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "cat", result.Prompt)
+	assert.Equal(t, "dog", result.NegativePrompt)
+}
+
+func TestParseNovelAIMetadataErrors(t *testing.T) {
+	parser := NewPromptParser()
+
+	_, err := parser.ParseNovelAIMetadata(map[string]string{"Description": "cat"})
+	assert.EqualError(t, err, "no NovelAI comment found")
+
+	_, err = parser.ParseNovelAIMetadata(map[string]string{"Comment": "cat"})
+	assert.ErrorContains(t, err, "invalid NovelAI comment: ")
+}
@@ -0,0 +1,84 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseNovelAIPrompt(t *testing.T) {
+	tests := []struct {
+		input  string
+		result ParsedPrompt
+	}{
+		{
+			"{abc}, [xyz]",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "abc", Weight: 1.05}, {Tag: "xyz", Weight: 0.9523809523809523}},
+			},
+		},
+		{
+			"{{artist (style)}}",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "artist (style)", Weight: 1.1025}},
+			},
+		},
+		{
+			"1.5::abc, xyz::, mno, -1::ugly ::",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "abc", Weight: 1.5},
+					{Tag: "xyz", Weight: 1.5},
+					{Tag: "mno", Weight: 1},
+					{Tag: "ugly", Weight: -1},
+				},
+			},
+		},
+		{
+			"abc 2::{xyz",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "abc", Weight: 1}, {Tag: "xyz", Weight: 2.1}},
+			},
+		},
+		{
+			"ratio 16:9, (abc:1.5)",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "ratio 16:9", Weight: 1}, {Tag: "(abc:1.5)", Weight: 1}},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+	parser.SetDialect(NovelAI)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParsePrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, *result)
+		})
+	}
+}
+
+func TestNovelAIPromptToString(t *testing.T) {
+	tests := []struct {
+		input  string
+		result string
+	}{
+		{"{ abc },,[xyz ]", "{abc}, [xyz]"},
+		{"1.5 :: abc , xyz ::", "1.5::abc, xyz::"},
+		{"{abc 0.5::xyz}", "{abc, 0.5::xyz::}"},
+		{"\\{abc\\}, (xyz)", "\\{abc\\}, (xyz)"},
+	}
+
+	parser := NewPromptParser()
+	parser.SetDialect(NovelAI)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.BeautifyPrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
@@ -0,0 +1,42 @@
+package parser
+
+import (
+	"strings"
+)
+
+const negativePromptPrefix = "Negative prompt:"
+
+type ParsedPromptPair struct {
+	Positive *ParsedPrompt `json:"positive"`
+	Negative *ParsedPrompt `json:"negative"`
+}
+
+// SplitPromptPair splits a pasted block at the "Negative prompt:" line.
+func SplitPromptPair(input string) (positive string, negative string) {
+	lines := strings.Split(strings.ReplaceAll(input, "\r\n", "\n"), "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(strings.TrimSpace(line), negativePromptPrefix) {
+			lines[i] = strings.TrimPrefix(strings.TrimSpace(line), negativePromptPrefix)
+			return strings.TrimSpace(strings.Join(lines[:i], "\n")), strings.TrimSpace(strings.Join(lines[i:], "\n"))
+		}
+	}
+
+	return strings.TrimSpace(input), ""
+}
+
+func (parser *PromptParser) ParsePromptPair(positive string, negative string) (*ParsedPromptPair, error) {
+	parsedPositive, err := parser.ParsePrompt(positive)
+	if err != nil {
+		return &ParsedPromptPair{}, err
+	}
+
+	parsedNegative, err := parser.ParsePrompt(negative)
+	if err != nil {
+		return &ParsedPromptPair{}, err
+	}
+
+	return &ParsedPromptPair{
+		Positive: parsedPositive,
+		Negative: parsedNegative,
+	}, nil
+}
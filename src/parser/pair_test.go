@@ -0,0 +1,44 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSplitPromptPair(t *testing.T) {
+	tests := []struct {
+		input    string
+		positive string
+		negative string
+	}{
+		{"abc, xyz", "abc, xyz", ""},
+		{"abc,\nxyz\nNegative prompt: mno", "abc,\nxyz", "mno"},
+		{"abc\r\n  Negative prompt:mno,\nxyz", "abc", "mno,\nxyz"},
+		{"Negative prompt: mno", "", "mno"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			positive, negative := SplitPromptPair(test.input)
+			assert.Equal(t, test.positive, positive)
+			assert.Equal(t, test.negative, negative)
+		})
+	}
+}
+
+func TestParsePromptPair(t *testing.T) {
+	parser := NewPromptParser()
+
+	result, err := parser.ParsePromptPair("(abc), <lora:file:1>", "[xyz]")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, ParsedPromptPair{
+		Positive: &ParsedPrompt{
+			Tags:  []*PromptTag{{Tag: "abc", Weight: 1.1}},
+			Loras: []*PromptModel{{Filename: "file", Multiplier: 1}},
+		},
+		Negative: &ParsedPrompt{
+			Tags: []*PromptTag{{Tag: "xyz", Weight: 0.9090909090909091}},
+		},
+	}, *result)
+}
@@ -13,6 +13,8 @@ import (
 	"github.com/junte/stable-diffusion-prompt-parser/src/reader"
 )
 
+var wildcardRegex = regexp.MustCompile(`^__(\S+?)__$`)
+
 const DefaultMultiplier = 0.5
 const DefaultWeight = 1
 
@@ -22,11 +24,11 @@ func (parser *PromptParser) escapeToken(token string) string {
 
 func (parser *PromptParser) parseTagPrompt(reader *reader.TokenReader) (*prompt, error) {
 	tokens := []string{}
-	invalidTokens := []string{"(", ")", "[", "]", "<", ">", ":", ",", "|", ""}
+	invalidTokens := []string{"(", ")", "[", "]", "<", ">", ":", ",", "|", "BREAK", ""}
 	for {
 		token := reader.GetToken()
 
-		if slices.Contains(invalidTokens, token) {
+		if slices.Contains(invalidTokens, token) || slices.Contains(regionKeywords, token) {
 			break
 		}
 
@@ -42,16 +44,92 @@ func (parser *PromptParser) parseTagPrompt(reader *reader.TokenReader) (*prompt,
 		tokens[i] = parser.escapeToken(token)
 	}
 
+	name := strings.Join(tokens, " ")
+	if slices.Contains(parser.embeddings, name) {
+		return &prompt{
+			kind: embedding,
+			name: name,
+		}, nil
+	}
+
+	// wildcards inside a tag split it into a phrase: red __color__ hair
+	contents := []*prompt{}
+	start := 0
+	for i, token := range tokens {
+		matches := wildcardRegex.FindStringSubmatch(token)
+		if len(matches) == 0 {
+			continue
+		}
+
+		if start < i {
+			contents = append(contents, &prompt{kind: tag, name: strings.Join(tokens[start:i], " "), tokens: tokens[start:i]})
+		}
+		contents = append(contents, &prompt{kind: wildcard, name: matches[1]})
+		start = i + 1
+	}
+
+	if len(contents) == 0 {
+		return &prompt{
+			kind:   tag,
+			name:   name,
+			tokens: tokens,
+		}, nil
+	}
+
+	if start < len(tokens) {
+		contents = append(contents, &prompt{kind: tag, name: strings.Join(tokens[start:], " "), tokens: tokens[start:]})
+	}
+
+	if len(contents) == 1 {
+		return contents[0], nil
+	}
+
 	return &prompt{
-		kind:   tag,
-		name:   strings.Join(tokens, " "),
-		tokens: tokens,
+		kind:     phrase,
+		contents: contents,
+	}, nil
+}
+
+func (parser *PromptParser) parseJoinedContents(reader *reader.TokenReader) ([]*prompt, error) {
+	contents, err := parser.parsePromptContents(reader, false)
+	if err != nil {
+		return nil, err
+	}
+
+	// RECOVER: (a|b) (alternation is only supported in [a|b])
+	for reader.GetToken() == "|" {
+		reader.NextToken()
+		newContents, err := parser.parsePromptContents(reader, false)
+		if err != nil {
+			return nil, err
+		}
+
+		contents = append(contents, newContents...)
+	}
+
+	return contents, nil
+}
+
+func (parser *PromptParser) parseEmbeddingPrompt(reader *reader.TokenReader) (*prompt, error) {
+	reader.NextToken()
+	reader.NextToken()
+	name, err := parser.parseContentToken(reader, "embedding")
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	reader.NextToken()
+
+	return &prompt{
+		kind:   embedding,
+		name:   name,
+		prefix: "embedding:",
 	}, nil
 }
 
 func (parser *PromptParser) parsePositivePrompt(reader *reader.TokenReader) (*prompt, error) {
 	reader.NextToken()
-	contents, err := parser.parsePromptContents(reader, false)
+	contents, err := parser.parseJoinedContents(reader)
 	if err != nil {
 		return &prompt{}, fmt.Errorf("%v", err)
 	}
@@ -92,7 +170,7 @@ func (parser *PromptParser) parsePositivePrompt(reader *reader.TokenReader) (*pr
 		}
 
 		// RECOVER: (a:b)
-		newContents, err := parser.parsePromptContents(reader, false)
+		newContents, err := parser.parseJoinedContents(reader)
 		if err != nil {
 			return &prompt{}, fmt.Errorf("%v", err)
 		}
@@ -140,6 +218,14 @@ func (parser *PromptParser) parseNegativePrompt(reader *reader.TokenReader) (*pr
 		return &prompt{}, fmt.Errorf("%v", err)
 	}
 
+	if reader.GetToken() == "|" {
+		return parser.parseAlternatePrompt(reader, contents)
+	}
+
+	if reader.GetToken() == ":" {
+		return parser.parseScheduledPrompt(reader, contents)
+	}
+
 	if reader.GetToken() == "]" || reader.GetToken() == "}" || reader.GetToken() == "" {
 		reader.NextToken()
 	}
@@ -150,6 +236,108 @@ func (parser *PromptParser) parseNegativePrompt(reader *reader.TokenReader) (*pr
 	}, nil
 }
 
+func (parser *PromptParser) parseAlternatePrompt(reader *reader.TokenReader, first []*prompt) (*prompt, error) {
+	options := [][]*prompt{first}
+	for reader.GetToken() == "|" {
+		reader.NextToken()
+		option, err := parser.parsePromptContents(reader, false)
+		if err != nil {
+			return &prompt{}, fmt.Errorf("%v", err)
+		}
+
+		options = append(options, option)
+	}
+
+	if reader.GetToken() == "]" || reader.GetToken() == "}" /* RECOVER: Expected ] but } found */ || reader.GetToken() == "" /* RECOVER: missing ] */ {
+		reader.NextToken()
+	}
+
+	return &prompt{
+		kind:    alternate,
+		options: options,
+	}, nil
+}
+
+func (parser *PromptParser) parseScheduledPrompt(reader *reader.TokenReader, from []*prompt) (*prompt, error) {
+	reader.NextToken()
+
+	// [to:when] is a shorthand for [:to:when]
+	tokens, err := reader.GetMultipleTokens(2)
+	if err == nil && tokens[1] == "]" {
+		if when, err := strconv.ParseFloat(tokens[0], 64); err == nil {
+			reader.NextToken()
+			reader.NextToken()
+			return &prompt{
+				kind: scheduled,
+				to:   from,
+				when: when,
+			}, nil
+		}
+	}
+
+	to, err := parser.parsePromptContents(reader, false)
+	if err != nil {
+		return &prompt{}, fmt.Errorf("%v", err)
+	}
+
+	if reader.GetToken() != ":" {
+		// RECOVER: [a:b] (not a schedule)
+		if reader.GetToken() == "]" || reader.GetToken() == "}" || reader.GetToken() == "" {
+			reader.NextToken()
+		}
+
+		return &prompt{
+			kind:     negativeWeight,
+			contents: append(from, to...),
+		}, nil
+	}
+
+	reader.NextToken()
+
+	// RECOVER: [a:b:] and [a:b:c] (the step is empty or not a number, so not a schedule)
+	step := strings.ReplaceAll(strings.ReplaceAll(reader.GetToken(), " ", ""), ",", ".")
+	if _, err := strconv.ParseFloat(step, 64); err != nil {
+		contents := append(from, to...)
+		for reader.GetToken() != "]" && reader.GetToken() != "}" && reader.GetToken() != "" {
+			rest, err := parser.parsePromptContents(reader, false)
+			if err != nil {
+				return &prompt{}, fmt.Errorf("%v", err)
+			}
+
+			contents = append(contents, rest...)
+			if reader.GetToken() != ":" {
+				break
+			}
+			reader.NextToken()
+		}
+
+		if reader.GetToken() == "]" || reader.GetToken() == "}" || reader.GetToken() == "" {
+			reader.NextToken()
+		}
+
+		return &prompt{
+			kind:     negativeWeight,
+			contents: contents,
+		}, nil
+	}
+
+	when, err := parser.parseNumber(reader, "step")
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	if reader.GetToken() == "]" || reader.GetToken() == "}" /* RECOVER: Expected ] but } found */ || reader.GetToken() == "" /* RECOVER: missing ] */ {
+		reader.NextToken()
+	}
+
+	return &prompt{
+		kind:     scheduled,
+		contents: from,
+		to:       to,
+		when:     when,
+	}, nil
+}
+
 func (parser *PromptParser) parseContentToken(reader *reader.TokenReader, name string) (string, error) {
 	token := reader.GetToken()
 	switch token {
@@ -172,6 +360,17 @@ func (parser *PromptParser) parseFilename(reader *reader.TokenReader) (filename
 }
 
 func (parser *PromptParser) parseNumber(reader *reader.TokenReader, name string) (number float64, err error) {
+	switch name {
+	case "multiplier":
+		return parser.parseNumberWithDefault(reader, name, DefaultMultiplier)
+	case "weight":
+		return parser.parseNumberWithDefault(reader, name, DefaultWeight)
+	}
+
+	return parser.parseNumberWithDefault(reader, name, 0)
+}
+
+func (parser *PromptParser) parseNumberWithDefault(reader *reader.TokenReader, name string, defaultNumber float64) (number float64, err error) {
 	isInt := func(s string) bool {
 		i, err := strconv.ParseInt(s, 10, 64)
 		return err == nil && strconv.FormatInt(i, 10) == s
@@ -212,12 +411,7 @@ func (parser *PromptParser) parseNumber(reader *reader.TokenReader, name string)
 
 	switch reader.GetToken() {
 	case ")", ">", ":":
-		switch name {
-		case "multiplier":
-			return DefaultMultiplier, nil
-		case "weight":
-			return DefaultWeight, nil
-		}
+		return defaultNumber, nil
 	}
 
 	token, err := parser.parseContentToken(reader, name)
@@ -235,12 +429,7 @@ func (parser *PromptParser) parseNumber(reader *reader.TokenReader, name string)
 
 	number, err = strconv.ParseFloat(token, 64)
 	if err != nil {
-		switch name {
-		case "multiplier":
-			number = DefaultMultiplier
-		case "weight":
-			number = DefaultWeight
-		}
+		number = defaultNumber
 	}
 
 	reader.NextToken()
@@ -249,6 +438,7 @@ func (parser *PromptParser) parseNumber(reader *reader.TokenReader, name string)
 }
 
 func (parser *PromptParser) parseAnglePrompt(reader *reader.TokenReader, kind string) (*prompt, error) {
+	network := parser.findNetwork(kind)
 	reader.NextToken()
 	reader.NextToken()
 	if reader.GetToken() != ":" {
@@ -261,34 +451,137 @@ func (parser *PromptParser) parseAnglePrompt(reader *reader.TokenReader, kind st
 		return &prompt{}, err
 	}
 
-	if reader.GetToken() == ":" {
+	multipliers, arguments := []float64{}, []string{}
+	for reader.GetToken() == ":" {
 		reader.NextToken()
-		multiplier, err := parser.parseNumber(reader, "multiplier")
+
+		// named argument: <lora:file:unet=0.5>
+		if key, value, found := strings.Cut(reader.GetToken(), "="); found {
+			arguments = append(arguments, strings.TrimSpace(key)+"="+parser.escapeToken(strings.TrimSpace(value)))
+			reader.NextToken()
+			continue
+		}
+
+		// RECOVER: <lora:file:1:2:3:4> (extra positional arguments are kept as is)
+		if len(multipliers) == network.Args {
+			if reader.GetToken() == ":" || reader.GetToken() == ">" {
+				arguments = append(arguments, "")
+				continue
+			}
+
+			argument, err := parser.parseContentToken(reader, "argument")
+			if err != nil {
+				return &prompt{}, err
+			}
+
+			reader.NextToken()
+			arguments = append(arguments, argument)
+			continue
+		}
+
+		multiplier, err := parser.parseNumberWithDefault(reader, "multiplier", network.DefaultMultiplier)
 		if err != nil {
 			return &prompt{}, err
 		}
 
-		if reader.GetToken() == ">" {
-			reader.NextToken()
-			return &prompt{
-				kind:       kind,
-				filename:   filename,
-				multiplier: multiplier,
-			}, nil
+		multipliers = append(multipliers, multiplier)
+	}
+
+	if reader.GetToken() != ">" {
+		reader.NextToken()
+		return &prompt{}, errors.New("> expected")
+	}
+	reader.NextToken()
+
+	content := &prompt{
+		kind:     network.Name,
+		filename: filename,
+	}
+
+	if kind != network.Name {
+		content.alias = kind
+	}
+
+	if len(arguments) > 0 {
+		content.arguments = arguments
+	}
+
+	switch network.Name {
+	case lora, hypernet:
+		content.multiplier = network.DefaultMultiplier
+		if len(multipliers) > 0 {
+			content.multiplier = multipliers[0]
+		} else if index := slices.IndexFunc(arguments, func(argument string) bool { return strings.HasPrefix(argument, "te=") }); index >= 0 {
+			// <lora:file:te=0.8> is the same as <lora:file:0.8>
+			if multiplier, err := strconv.ParseFloat(strings.TrimPrefix(arguments[index], "te="), 64); err == nil {
+				content.multiplier = multiplier
+				content.arguments = slices.Delete(arguments, index, index+1)
+				if len(content.arguments) == 0 {
+					content.arguments = nil
+				}
+			}
+		}
+
+		if len(multipliers) > 1 {
+			content.multipliers = multipliers[1:]
+		}
+	default:
+		// missing multipliers are filled in by evaluate, the prompt is written back as is
+		content.kind = extraNetwork
+		content.name = network.Name
+		if len(multipliers) > 0 {
+			content.multipliers = multipliers
 		}
 	}
 
-	if reader.GetToken() == ">" {
+	return content, nil
+}
+
+func (parser *PromptParser) parseNetworkPrompt(reader *reader.TokenReader) (*prompt, error) {
+	reader.NextToken()
+	name := reader.GetToken()
+	reader.NextToken()
+	if reader.GetToken() != ":" {
+		return &prompt{}, errors.New(": expected")
+	}
+
+	reader.NextToken()
+	filename, err := parser.parseFilename(reader)
+	if err != nil {
+		return &prompt{}, err
+	}
+
+	arguments := []string{}
+	for reader.GetToken() == ":" {
 		reader.NextToken()
-		return &prompt{
-			kind:       kind,
-			filename:   filename,
-			multiplier: DefaultMultiplier,
-		}, nil
+
+		// RECOVER: <name:file::1> (empty argument)
+		if reader.GetToken() == ":" || reader.GetToken() == ">" {
+			arguments = append(arguments, "")
+			continue
+		}
+
+		argument, err := parser.parseContentToken(reader, "argument")
+		if err != nil {
+			return &prompt{}, err
+		}
+
+		reader.NextToken()
+		arguments = append(arguments, argument)
+	}
+
+	if reader.GetToken() != ">" {
+		reader.NextToken()
+		return &prompt{}, errors.New("> expected")
 	}
 	reader.NextToken()
 
-	return &prompt{}, errors.New("> expected")
+	return &prompt{
+		kind:      extraNetwork,
+		name:      name,
+		filename:  filename,
+		arguments: arguments,
+	}, nil
 }
 
 func (parser *PromptParser) parsePromptContent(reader *reader.TokenReader, topLevel bool) (*prompt, error) {
@@ -303,21 +596,36 @@ func (parser *PromptParser) parsePromptContent(reader *reader.TokenReader, topLe
 		if err == nil {
 			// RECOVER: (topLevel === false) A <a:b:c> cannot be nested in other prompt
 			modelName := tokens[1]
-			switch modelName {
-			case lora, hypernet:
+			if parser.findNetwork(modelName) != nil {
 				return parser.parseAnglePrompt(reader, modelName)
-			default:
-				// RECOVER: unknown model name
-				reader.NextToken()
-				return &prompt{}, nil
 			}
+
+			if tokens, err := reader.GetMultipleTokens(3); err == nil && tokens[2] == ":" {
+				return parser.parseNetworkPrompt(reader)
+			}
+
+			// RECOVER: unknown model name
+			reader.NextToken()
+			return &prompt{}, nil
 		}
 		reader.NextToken()
 		return &prompt{}, nil
 	case ",":
 		return &prompt{}, errors.New("prompt expected")
+	case "BREAK":
+		reader.NextToken()
+		return &prompt{kind: chunkBreak}, nil
+	case regionCommon, regionBase, regionColumn, regionRow:
+		reader.NextToken()
+		return &prompt{kind: regionBreak, name: token}, nil
 	default:
-		tagPrompt, err := parser.parseTagPrompt(reader)
+		var tagPrompt *prompt
+		var err error
+		if tokens, _ := reader.GetMultipleTokens(2); token == "embedding" && len(tokens) == 2 && tokens[1] == ":" {
+			tagPrompt, err = parser.parseEmbeddingPrompt(reader)
+		} else {
+			tagPrompt, err = parser.parseTagPrompt(reader)
+		}
 		if err != nil {
 			return &prompt{}, err
 		}
@@ -368,13 +676,64 @@ func (parser *PromptParser) parsePromptContents(reader *reader.TokenReader, topL
 }
 
 func (parser *PromptParser) parse(input string) (*prompt, error) {
-	input = strings.ReplaceAll(input, "|", ",")
+	if !parser.normalize {
+		return parser.parseDialect(input)
+	}
+
+	normalized, diagnostics := Normalize(input)
+	root, err := parser.parseDialect(normalized)
+	if err != nil {
+		return root, err
+	}
+	root.diagnostics = diagnostics
+
+	return root, nil
+}
+
+func (parser *PromptParser) parseDialect(input string) (*prompt, error) {
+	switch parser.dialect {
+	case NovelAI:
+		return parser.parseNovelAI(input)
+	case Compel:
+		return parser.parseCompel(input)
+	case Midjourney:
+		return parser.parseMidjourney(input)
+	}
+
+	parts := regexp.MustCompile(`\bAND\b`).Split(input, -1)
+	if len(parts) == 1 {
+		return parser.parseSubPrompt(input)
+	}
+
+	composed := &prompt{}
+	for _, part := range parts {
+		weight := 1.0
+		matches := regexp.MustCompile(`(?s)^(.*?):\s*([-+]?(?:\d+\.?|\d*\.\d+))\s*$`).FindStringSubmatch(part)
+		if len(matches) > 0 {
+			part = matches[1]
+			weight, _ = strconv.ParseFloat(matches[2], 64)
+		}
+
+		subPrompt, err := parser.parseSubPrompt(part)
+		if err != nil {
+			return composed, err
+		}
+
+		subPrompt.kind = composable
+		subPrompt.weight = weight
+		composed.contents = append(composed.contents, subPrompt)
+	}
+
+	return composed, nil
+}
+
+func (parser *PromptParser) parseSubPrompt(input string) (*prompt, error) {
 	prompt := &prompt{}
 	reader := reader.NewTokenReader(input)
 
 	for {
 		switch reader.GetToken() {
-		case ")", "]", ">", ":":
+		case ")", "]", ">", ":", "|":
 			reader.NextToken()
 			continue
 		case "":
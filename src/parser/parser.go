@@ -1,9 +1,27 @@
 package parser
 
-type PromptParser struct{}
+import (
+	"fmt"
+	"reflect"
+)
+
+type PromptParser struct {
+	dialect    Dialect
+	embeddings []string
+	networks   []*NetworkKind
+	normalize  bool
+}
 
 func NewPromptParser() *PromptParser {
-	return &PromptParser{}
+	parser := &PromptParser{dialect: A1111}
+	parser.RegisterNetwork(&NetworkKind{Name: lora, Aliases: []string{"lyco", "lycoris"}, DefaultMultiplier: DefaultMultiplier, Args: 3})
+	parser.RegisterNetwork(&NetworkKind{Name: hypernet, DefaultMultiplier: DefaultMultiplier, Args: 1})
+
+	return parser
+}
+
+func (parser *PromptParser) AddEmbeddings(names ...string) {
+	parser.embeddings = append(parser.embeddings, names...)
 }
 
 func (parser *PromptParser) ParsePrompt(input string) (*ParsedPrompt, error) {
@@ -12,7 +30,46 @@ func (parser *PromptParser) ParsePrompt(input string) (*ParsedPrompt, error) {
 		return &ParsedPrompt{}, err
 	}
 
-	return parser.evaluate(prompt), nil
+	return parser.evaluate(prompt, 0, 0), nil
+}
+
+func (parser *PromptParser) EvaluateAtStep(input string, step int, totalSteps int) (*ParsedPrompt, error) {
+	if step < 1 || step > totalSteps {
+		return &ParsedPrompt{}, fmt.Errorf("step %d out of range 1..%d", step, totalSteps)
+	}
+
+	prompt, err := parser.parse(input)
+	if err != nil {
+		return &ParsedPrompt{}, err
+	}
+
+	return parser.evaluate(prompt, step, totalSteps), nil
+}
+
+func (parser *PromptParser) PromptTimeline(input string, totalSteps int) ([]*TimelineEntry, error) {
+	if totalSteps < 1 {
+		return nil, fmt.Errorf("total steps must be positive, got %d", totalSteps)
+	}
+
+	prompt, err := parser.parse(input)
+	if err != nil {
+		return nil, err
+	}
+
+	timeline := []*TimelineEntry{}
+	for step := 1; step <= totalSteps; step++ {
+		evaluated := parser.evaluate(prompt, step, totalSteps)
+
+		last := len(timeline) - 1
+		if last >= 0 && reflect.DeepEqual(timeline[last].Prompt, evaluated) {
+			timeline[last].ToStep = step
+			continue
+		}
+
+		timeline = append(timeline, &TimelineEntry{FromStep: step, ToStep: step, Prompt: evaluated})
+	}
+
+	return timeline, nil
 }
 
 func (parser *PromptParser) BeautifyPrompt(input string) (string, error) {
@@ -297,6 +297,173 @@ func TestParseNegativePrompt(t *testing.T) {
 				},
 			},
 		},
+		{
+			"[abc:xyz:0.4]",
+			prompt{
+				kind: "sched",
+				contents: []*prompt{
+					{
+						kind:   "tag",
+						name:   "abc",
+						tokens: []string{"abc"},
+					},
+				},
+				to: []*prompt{
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+				},
+				when: 0.4,
+			},
+		},
+		{
+			"[abc::10]",
+			prompt{
+				kind: "sched",
+				contents: []*prompt{
+					{
+						kind:   "tag",
+						name:   "abc",
+						tokens: []string{"abc"},
+					},
+				},
+				when: 10,
+			},
+		},
+		{
+			"[:xyz:5]",
+			prompt{
+				kind: "sched",
+				to: []*prompt{
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+				},
+				when: 5,
+			},
+		},
+		{
+			"[xyz:5]",
+			prompt{
+				kind: "sched",
+				to: []*prompt{
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+				},
+				when: 5,
+			},
+		},
+		{
+			"[abc|xyz]",
+			prompt{
+				kind: "alt",
+				options: [][]*prompt{
+					{
+						{
+							kind:   "tag",
+							name:   "abc",
+							tokens: []string{"abc"},
+						},
+					},
+					{
+						{
+							kind:   "tag",
+							name:   "xyz",
+							tokens: []string{"xyz"},
+						},
+					},
+				},
+			},
+		},
+		{
+			"[abc:xyz:]",
+			prompt{
+				kind: "nw",
+				contents: []*prompt{
+					{
+						kind:   "tag",
+						name:   "abc",
+						tokens: []string{"abc"},
+					},
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+				},
+			},
+		},
+		{
+			"[abc:xyz:1girl]",
+			prompt{
+				kind: "nw",
+				contents: []*prompt{
+					{
+						kind:   "tag",
+						name:   "abc",
+						tokens: []string{"abc"},
+					},
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+					{
+						kind:   "tag",
+						name:   "1girl",
+						tokens: []string{"1girl"},
+					},
+				},
+			},
+		},
+		{
+			"[abc:xyz:mno]",
+			prompt{
+				kind: "nw",
+				contents: []*prompt{
+					{
+						kind:   "tag",
+						name:   "abc",
+						tokens: []string{"abc"},
+					},
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+					{
+						kind:   "tag",
+						name:   "mno",
+						tokens: []string{"mno"},
+					},
+				},
+			},
+		},
+		{
+			"[abc:xyz]",
+			prompt{
+				kind: "nw",
+				contents: []*prompt{
+					{
+						kind:   "tag",
+						name:   "abc",
+						tokens: []string{"abc"},
+					},
+					{
+						kind:   "tag",
+						name:   "xyz",
+						tokens: []string{"xyz"},
+					},
+				},
+			},
+		},
 	}
 
 	parser := NewPromptParser()
@@ -452,6 +619,26 @@ func TestParseAnglePrompt(t *testing.T) {
 				multiplier: 0.5,
 			},
 		},
+		{
+			"<lora:file:0.8:0.5:16>",
+			"lora",
+			prompt{
+				kind:        "lora",
+				filename:    "file",
+				multiplier:  0.8,
+				multipliers: []float64{0.5, 16},
+			},
+		},
+		{
+			"<lora:file:te=0.8:lbw=MIDD>",
+			"lora",
+			prompt{
+				kind:       "lora",
+				filename:   "file",
+				multiplier: 0.8,
+				arguments:  []string{"lbw=MIDD"},
+			},
+		},
 		{
 			"< lora : ?file,name[v.1]  : .5 >",
 			"lora",
@@ -498,10 +685,35 @@ func TestParsePromptContent(t *testing.T) {
 		{
 			"<abc:xyz>",
 			true,
-			prompt{},
+			prompt{
+				kind:      "network",
+				name:      "abc",
+				filename:  "xyz",
+				arguments: []string{},
+			},
 		},
 		{
-			"<abc:1.5>",
+			"<abc:xyz:1.5:mno>",
+			true,
+			prompt{
+				kind:      "network",
+				name:      "abc",
+				filename:  "xyz",
+				arguments: []string{"1.5", "mno"},
+			},
+		},
+		{
+			"<lyco:xyz:1.5>",
+			true,
+			prompt{
+				kind:       "lora",
+				alias:      "lyco",
+				filename:   "xyz",
+				multiplier: 1.5,
+			},
+		},
+		{
+			"<abc>",
 			true,
 			prompt{},
 		},
@@ -750,6 +962,167 @@ func TestParsePrompt(t *testing.T) {
 				},
 			},
 		},
+		{
+			"[abc:(xyz):0.5]",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "abc",
+						Weight: 1,
+					},
+					{
+						Tag:    "xyz",
+						Weight: 1.1,
+					},
+				},
+			},
+		},
+		{
+			"[abc|xyz], (mno|[abc|xyz])",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "abc",
+						Weight: 1,
+					},
+					{
+						Tag:    "xyz",
+						Weight: 1,
+					},
+					{
+						Tag:    "mno",
+						Weight: 1.1,
+					},
+					{
+						Tag:    "abc",
+						Weight: 1.1,
+					},
+					{
+						Tag:    "xyz",
+						Weight: 1.1,
+					},
+				},
+			},
+		},
+		{
+			"abc :1.2 AND (xyz), <lora:file:0.5> :0.8",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "abc",
+						Weight: 1,
+					},
+					{
+						Tag:    "xyz",
+						Weight: 1.1,
+					},
+				},
+				Loras: []*PromptModel{{Filename: "file", Multiplier: 0.5}},
+				SubPrompts: []*SubPrompt{
+					{
+						Weight: 1.2,
+						Prompt: &ParsedPrompt{
+							Tags: []*PromptTag{{Tag: "abc", Weight: 1}},
+						},
+					},
+					{
+						Weight: 0.8,
+						Prompt: &ParsedPrompt{
+							Tags:  []*PromptTag{{Tag: "xyz", Weight: 1.1}},
+							Loras: []*PromptModel{{Filename: "file", Multiplier: 0.5}},
+						},
+					},
+				},
+			},
+		},
+		{
+			"abc xyz BREAK (mno BREAK abc)",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "abc xyz",
+						Weight: 1,
+						Chunk:  0,
+					},
+					{
+						Tag:    "mno",
+						Weight: 1.1,
+						Chunk:  1,
+					},
+					{
+						Tag:    "abc",
+						Weight: 1.1,
+						Chunk:  2,
+					},
+				},
+				Breaks: 2,
+			},
+		},
+		{
+			"kafka \\(honkai\\), (\\[abc\\]:1.5)",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "kafka (honkai)",
+						Weight: 1,
+					},
+					{
+						Tag:    "[abc]",
+						Weight: 1.5,
+					},
+				},
+			},
+		},
+		{
+			"abc, (__mno/xyz__), red __color__ hair",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "abc",
+						Weight: 1,
+					},
+					{
+						Tag:    "red hair",
+						Weight: 1,
+					},
+				},
+				Wildcards: []*PromptWildcard{
+					{
+						Name:   "mno/xyz",
+						Weight: 1.1,
+					},
+					{
+						Name:   "color",
+						Weight: 1,
+					},
+				},
+			},
+		},
+		{
+			"(long __color__ hair), __a__ __b__",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "long hair",
+						Weight: 1.1,
+					},
+				},
+				Wildcards: []*PromptWildcard{
+					{
+						Name:   "color",
+						Weight: 1.1,
+					},
+					{
+						Name:   "a",
+						Weight: 1,
+					},
+					{
+						Name:   "b",
+						Weight: 1,
+					},
+				},
+			},
+		},
 		{
 			"abc, <lora",
 			ParsedPrompt{
@@ -800,6 +1173,33 @@ func TestPromptToString(t *testing.T) {
 		{"<lora:file.name:1.5.>", "<lora:file.name:1.5>"},
 		{"<lora:file.name:1..5>", "<lora:file.name:1.5>"},
 		{"<lora:file.name:1.5.2>", "<lora:file.name:1.5>"},
+		{"[ abc : xyz : 0.4 ]", "[abc:xyz:.4]"},
+		{"[abc::10]", "[abc::10]"},
+		{"[:xyz:5]", "[xyz:5]"},
+		{"mno [abc:(xyz):5]", "mno, [abc:(xyz):5]"},
+		{"[ abc | (xyz) |mno]", "[abc|(xyz)|mno]"},
+		{"abc | xyz", "abc, xyz"},
+		{"(abc|xyz)", "(abc, xyz)"},
+		{"abc :1.2 AND (xyz) : 0.8", "abc :1.2 AND (xyz) :.8"},
+		{"abc,,xyz AND mno:1", "abc, xyz AND mno"},
+		{"abc BREAK xyz", "abc BREAK xyz"},
+		{"(abc)BREAK,(xyz)", "(abc), BREAK, (xyz)"},
+		{"abc,ADDCOMM,xyz ADDCOL mno", "abc ADDCOMM xyz ADDCOL mno"},
+		{"(abc) ADDBASE [xyz], ADDROW <lora:file:1>", "(abc) ADDBASE [xyz] ADDROW <lora:file:1>"},
+		{"ADDCOL, abc", "ADDCOL abc"},
+		{"abc, ADDCOLOR xyz, ADDROWS", "abc, ADDCOLOR xyz, ADDROWS"},
+		{"abc, embedding:xyz,mno", "abc, embedding:xyz, mno"},
+		{"<lyco:file>", "<lyco:file:.5>"},
+		{"abc <ti:file::xyz>", "abc, <ti:file::xyz>"},
+		{"<lora:file:0.8:0.5>", "<lora:file:.8:.5>"},
+		{"abc,__mno/xyz__", "abc, __mno/xyz__"},
+		{"(red __color__ hair), __a__ __b__", "(red __color__ hair), __a__ __b__"},
+		{"kafka \\(honkai\\) (abc)", "kafka \\(honkai\\), (abc)"},
+		{"(kafka \\(honkai\\):1.2)", "(kafka \\(honkai\\):1.2)"},
+		{"\\[abc\\:xyz\\] mno", "\\[abc\\:xyz\\] mno"},
+		{"abc\\\\ <lora:file\\:name>", "abc\\\\, <lora:file\\:name:.5>"},
+		{"<lora:file: 1 : unet = 0.5 : lbw=MIDD>", "<lora:file:1:unet=0.5:lbw=MIDD>"},
+		{"abc,<hypernet:file:1:2>,<lora:file:1:2:3:4>", "abc, <hypernet:file:1:2>, <lora:file:1:2:3:4>"},
 	}
 
 	parser := NewPromptParser()
@@ -812,3 +1212,299 @@ func TestPromptToString(t *testing.T) {
 		})
 	}
 }
+
+func TestEvaluateAtStep(t *testing.T) {
+	tests := []struct {
+		input  string
+		step   int
+		result ParsedPrompt
+	}{
+		{
+			"[abc:xyz:0.4]",
+			4,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "abc", Weight: 1}},
+			},
+		},
+		{
+			"[abc:xyz:0.4]",
+			5,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "xyz", Weight: 1}},
+			},
+		},
+		{
+			"mno, [abc::5]",
+			6,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "mno", Weight: 1}},
+			},
+		},
+		{
+			"([:xyz:2])",
+			3,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "xyz", Weight: 1.1}},
+			},
+		},
+		{
+			"[abc|xyz|mno]",
+			2,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "xyz", Weight: 1}},
+			},
+		},
+		{
+			"[abc|xyz|mno]",
+			4,
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "abc", Weight: 1}},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.EvaluateAtStep(test.input, test.step, 10)
+			assert.Equal(t, nil, err)
+			assert.True(t, reflect.DeepEqual(*result, test.result))
+		})
+	}
+
+	_, err := parser.EvaluateAtStep("abc", 11, 10)
+	assert.EqualError(t, err, "step 11 out of range 1..10")
+}
+
+func TestPromptTimeline(t *testing.T) {
+	parser := NewPromptParser()
+
+	result, err := parser.PromptTimeline("mno, [abc:xyz:0.4]", 10)
+	assert.Equal(t, nil, err)
+	assert.True(t, reflect.DeepEqual(result, []*TimelineEntry{
+		{
+			FromStep: 1,
+			ToStep:   4,
+			Prompt: &ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "mno", Weight: 1}, {Tag: "abc", Weight: 1}},
+			},
+		},
+		{
+			FromStep: 5,
+			ToStep:   10,
+			Prompt: &ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "mno", Weight: 1}, {Tag: "xyz", Weight: 1}},
+			},
+		},
+	}))
+}
+
+func TestBeautifyRoundTrip(t *testing.T) {
+	tests := []string{
+		"kafka \\(honkai\\), (abc:1.2)",
+		"\\[abc\\], [xyz|mno], <lora:file\\:name:.5>",
+		"abc \\<xyz\\>, mno\\|abc",
+		"abc ADDCOMM (xyz) ADDCOL mno, <lora:file:1> ADDROW abc",
+		"abc, ADDCOLOR xyz, ADDBASEMENT",
+	}
+
+	parser := NewPromptParser()
+
+	for _, test := range tests {
+		t.Run(test, func(t *testing.T) {
+			beautified, err := parser.BeautifyPrompt(test)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test, beautified)
+
+			parsed, err := parser.ParsePrompt(test)
+			assert.Equal(t, nil, err)
+			reparsed, err := parser.ParsePrompt(beautified)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, parsed, reparsed)
+		})
+	}
+}
+
+func TestParseEmbeddings(t *testing.T) {
+	tests := []struct {
+		input  string
+		result ParsedPrompt
+	}{
+		{
+			"embedding:abc",
+			ParsedPrompt{
+				Embeddings: []*PromptEmbedding{{Name: "abc", Weight: 1}},
+			},
+		},
+		{
+			"(embedding:abc:1.5), xyz",
+			ParsedPrompt{
+				Tags:       []*PromptTag{{Tag: "xyz", Weight: 1}},
+				Embeddings: []*PromptEmbedding{{Name: "abc", Weight: 1.5}},
+			},
+		},
+		{
+			"mno, [EasyNegative]",
+			ParsedPrompt{
+				Tags:       []*PromptTag{{Tag: "mno", Weight: 1}},
+				Embeddings: []*PromptEmbedding{{Name: "EasyNegative", Weight: 0.9090909090909091}},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+	parser.AddEmbeddings("EasyNegative")
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParsePrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.True(t, reflect.DeepEqual(*result, test.result))
+		})
+	}
+
+	beautified, err := parser.BeautifyPrompt("abc,EasyNegative,  embedding:xyz")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "abc, EasyNegative, embedding:xyz", beautified)
+}
+
+func TestRegisterNetwork(t *testing.T) {
+	parser := NewPromptParser()
+	parser.RegisterNetwork(&NetworkKind{Name: "ti", Aliases: []string{"textual"}, DefaultMultiplier: 1, Args: 2})
+
+	result, err := parser.ParsePrompt("abc, <ti:file:0.5>, <textual:file>, <ext:file:xyz>")
+	assert.Equal(t, nil, err)
+	assert.True(t, reflect.DeepEqual(*result, ParsedPrompt{
+		Tags: []*PromptTag{{Tag: "abc", Weight: 1}},
+		Networks: []*PromptNetwork{
+			{Kind: "ti", Filename: "file", Multipliers: []float64{0.5, 1}},
+			{Kind: "ti", Filename: "file", Multipliers: []float64{1, 1}},
+			{Kind: "ext", Filename: "file", Args: []string{"xyz"}},
+		},
+	}))
+
+	beautified, err := parser.BeautifyPrompt("abc, <ti:file:0.5>, <textual:file>")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, "abc, <ti:file:.5>, <textual:file>", beautified)
+}
+
+func TestParseLoraArguments(t *testing.T) {
+	textEncoder, unet := 0.8, 0.5
+
+	tests := []struct {
+		input  string
+		result ParsedPrompt
+	}{
+		{
+			"<lora:file:0.8:0.5>",
+			ParsedPrompt{
+				Loras: []*PromptModel{{Filename: "file", Multiplier: 0.8, TextEncoder: &textEncoder, UNet: &unet}},
+			},
+		},
+		{
+			"<lora:file:0.8:unet=0.5:lbw=MIDD>",
+			ParsedPrompt{
+				Loras: []*PromptModel{{
+					Filename:    "file",
+					Multiplier:  0.8,
+					TextEncoder: &textEncoder,
+					UNet:        &unet,
+					Args:        map[string]string{"unet": "0.5", "lbw": "MIDD"},
+				}},
+			},
+		},
+		{
+			"<lora:file:unet=0.5>",
+			ParsedPrompt{
+				Loras: []*PromptModel{{
+					Filename:    "file",
+					Multiplier:  0.5,
+					TextEncoder: &unet,
+					UNet:        &unet,
+					Args:        map[string]string{"unet": "0.5"},
+				}},
+			},
+		},
+		{
+			"<lora:file:lbw=MIDD>, <lora:file>",
+			ParsedPrompt{
+				Loras: []*PromptModel{
+					{Filename: "file", Multiplier: 0.5, Args: map[string]string{"lbw": "MIDD"}},
+					{Filename: "file", Multiplier: 0.5},
+				},
+			},
+		},
+		{
+			"<lora:file:0.3:0.3:16:te=0.8:unet=0.5:dyn=8>, <hypernet:file:1:unet=0.5>",
+			ParsedPrompt{
+				Loras: []*PromptModel{{
+					Filename:    "file",
+					Multiplier:  0.8,
+					TextEncoder: &textEncoder,
+					UNet:        &unet,
+					Args:        map[string]string{"te": "0.8", "unet": "0.5", "dyn": "8"},
+				}},
+				Hypernets: []*PromptModel{{Filename: "file", Multiplier: 1, Args: map[string]string{"unet": "0.5"}}},
+			},
+		},
+		{
+			"<lora:file:1:lbw=MIDD>",
+			ParsedPrompt{
+				Loras: []*PromptModel{{Filename: "file", Multiplier: 1, Args: map[string]string{"lbw": "MIDD"}}},
+			},
+		},
+		{
+			"abc, <hypernet:file:1:2>, <lora:file:0.8:0.5:16:7>, xyz",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{
+						Tag:    "abc",
+						Weight: 1,
+					},
+					{
+						Tag:    "xyz",
+						Weight: 1,
+					},
+				},
+				Loras: []*PromptModel{{
+					Filename:    "file",
+					Multiplier:  0.8,
+					TextEncoder: &textEncoder,
+					UNet:        &unet,
+					Args:        map[string]string{"dyn": "16", "4": "7"},
+				}},
+				Hypernets: []*PromptModel{{Filename: "file", Multiplier: 1, Args: map[string]string{"2": "2"}}},
+			},
+		},
+		{
+			"<lora:file:0.8:0.8:16>",
+			ParsedPrompt{
+				Loras: []*PromptModel{{
+					Filename:    "file",
+					Multiplier:  0.8,
+					TextEncoder: &textEncoder,
+					UNet:        &textEncoder,
+					Args:        map[string]string{"dyn": "16"},
+				}},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParsePrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, *result)
+		})
+	}
+}
+
+func TestSetDialect(t *testing.T) {
+	parser := NewPromptParser()
+	assert.Equal(t, nil, parser.SetDialect(NovelAI))
+	assert.EqualError(t, parser.SetDialect("xyz"), `unknown dialect "xyz"`)
+	assert.Equal(t, NovelAI, parser.dialect)
+}
@@ -0,0 +1,58 @@
+package parser
+
+// Regional Prompter keywords
+const (
+	regionCommon = "ADDCOMM"
+	regionBase   = "ADDBASE"
+	regionColumn = "ADDCOL"
+	regionRow    = "ADDROW"
+)
+
+var regionKeywords = []string{regionCommon, regionBase, regionColumn, regionRow}
+
+const (
+	CommonRegion = "common"
+	BaseRegion   = "base"
+	Region       = "region"
+)
+
+// evaluateRegions splits top level contents by region keywords: the part before ADDCOMM is
+// common for all regions, the part before ADDBASE is the base prompt, ADDCOL and ADDROW
+// start the next column and row.
+func (parser *PromptParser) evaluateRegions(contents []*prompt, step int, totalSteps int) []*PromptRegion {
+	var regions []*PromptRegion
+	row, column, start := 0, 0, 0
+
+	add := func(kind string, end int) {
+		evaluated := &ParsedPrompt{}
+		parser.evaluatePromptContents(contents[start:end], 1, parser.weightMultiplier(), step, totalSteps, evaluated)
+		regions = append(regions, &PromptRegion{Kind: kind, Row: row, Column: column, Prompt: evaluated})
+		start = end + 1
+	}
+
+	for i, content := range contents {
+		if content.kind != regionBreak {
+			continue
+		}
+
+		switch content.name {
+		case regionCommon:
+			add(CommonRegion, i)
+		case regionBase:
+			add(BaseRegion, i)
+		case regionColumn:
+			add(Region, i)
+			column++
+		case regionRow:
+			add(Region, i)
+			row, column = row+1, 0
+		}
+	}
+
+	if len(regions) == 0 {
+		return nil
+	}
+	add(Region, len(contents))
+
+	return regions
+}
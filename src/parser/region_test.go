@@ -0,0 +1,73 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseRegions(t *testing.T) {
+	tests := []struct {
+		input  string
+		result ParsedPrompt
+	}{
+		{
+			"abc ADDCOMM (xyz), <lora:file:1> ADDCOL mno ADDROW [abc] ADDCOL xyz",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "abc", Weight: 1},
+					{Tag: "xyz", Weight: 1.1},
+					{Tag: "mno", Weight: 1},
+					{Tag: "abc", Weight: 0.9090909090909091},
+					{Tag: "xyz", Weight: 1},
+				},
+				Loras: []*PromptModel{{Filename: "file", Multiplier: 1}},
+				Regions: []*PromptRegion{
+					{Kind: CommonRegion, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "abc", Weight: 1}}}},
+					{Kind: Region, Prompt: &ParsedPrompt{
+						Tags:  []*PromptTag{{Tag: "xyz", Weight: 1.1}},
+						Loras: []*PromptModel{{Filename: "file", Multiplier: 1}},
+					}},
+					{Kind: Region, Column: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "mno", Weight: 1}}}},
+					{Kind: Region, Row: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "abc", Weight: 0.9090909090909091}}}},
+					{Kind: Region, Row: 1, Column: 1, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "xyz", Weight: 1}}}},
+				},
+			},
+		},
+		{
+			"abc ADDBASE xyz BREAK mno ADDCOL",
+			ParsedPrompt{
+				Tags: []*PromptTag{
+					{Tag: "abc", Weight: 1},
+					{Tag: "xyz", Weight: 1},
+					{Tag: "mno", Weight: 1, Chunk: 1},
+				},
+				Breaks: 1,
+				Regions: []*PromptRegion{
+					{Kind: BaseRegion, Prompt: &ParsedPrompt{Tags: []*PromptTag{{Tag: "abc", Weight: 1}}}},
+					{Kind: Region, Prompt: &ParsedPrompt{
+						Tags:   []*PromptTag{{Tag: "xyz", Weight: 1}, {Tag: "mno", Weight: 1, Chunk: 1}},
+						Breaks: 1,
+					}},
+					{Kind: Region, Column: 1, Prompt: &ParsedPrompt{}},
+				},
+			},
+		},
+		{
+			"abc ADDCOLUMN xyz",
+			ParsedPrompt{
+				Tags: []*PromptTag{{Tag: "abc ADDCOLUMN xyz", Weight: 1}},
+			},
+		},
+	}
+
+	parser := NewPromptParser()
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := parser.ParsePrompt(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, *result)
+		})
+	}
+}
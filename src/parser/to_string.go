@@ -3,21 +3,57 @@ package parser
 import (
 	"fmt"
 	"regexp"
+	"strings"
 )
 
 func truncateZero(input string) string {
-	if input[0] == '0' {
+	if len(input) > 1 && input[0] == '0' {
 		return input[1:]
 	}
 
 	return input
 }
 
+func escapeName(name string) string {
+	return regexp.MustCompile(`([\\()\[\]:,|<>])`).ReplaceAllString(name, `\$1`)
+}
+
+func escapeFilename(filename string) string {
+	return regexp.MustCompile(`([\\:<>])`).ReplaceAllString(filename, `\$1`)
+}
+
+// phraseToString joins tags and wildcards of a phrase with spaces
+func phraseToString(content *prompt, escape func(string) string) string {
+	parts := make([]string, len(content.contents))
+	for i, part := range content.contents {
+		if part.kind == wildcard {
+			parts[i] = "__" + part.name + "__"
+		} else {
+			parts[i] = escape(part.name)
+		}
+	}
+
+	return strings.Join(parts, " ")
+}
+
+func (parser *PromptParser) networkName(content *prompt) string {
+	if content.alias != "" {
+		return content.alias
+	}
+
+	if content.kind == extraNetwork {
+		return content.name
+	}
+
+	return content.kind
+}
+
 func (parser *PromptParser) contentsToString(contents []*prompt) (result string) {
 	var lastPromptIsTag bool
 
 	for _, content := range contents {
-		if content.kind == tag && lastPromptIsTag {
+		isTag := content.kind == tag || content.kind == embedding || content.kind == wildcard || content.kind == group || content.kind == phrase
+		if isTag && lastPromptIsTag {
 			result += ", "
 		} else if result != "" {
 			result += " "
@@ -34,32 +70,96 @@ func (parser *PromptParser) contentsToString(contents []*prompt) (result string)
 				result += ":" + truncateZero(fmt.Sprintf("%v", content.weight))
 			}
 			result += ")"
+		case scheduled:
+			result += "["
+			if len(content.contents) > 0 {
+				result += parser.contentsToString(content.contents) + ":"
+			}
+			result += parser.contentsToString(content.to) + ":" + truncateZero(fmt.Sprintf("%v", content.when)) + "]"
+		case alternate:
+			options := make([]string, len(content.options))
+			for i, option := range content.options {
+				options[i] = parser.contentsToString(option)
+			}
+			result += "[" + strings.Join(options, "|") + "]"
+		case embedding:
+			result += content.prefix + escapeName(content.name)
+		case group, blend:
+			result += parser.contentsToString(content.contents)
+		case conjunction:
+			result += parser.toString(content)
+		case wildcard:
+			result += "__" + content.name + "__"
+		case phrase:
+			result += phraseToString(content, escapeName)
+		case chunkBreak:
+			result += "BREAK"
+		case regionBreak:
+			result += content.name
 		case lora, hypernet:
-			result += "<" + content.kind + ":" + content.filename
-			if content.multiplier != 0 {
+			result += "<" + parser.networkName(content) + ":" + escapeFilename(content.filename)
+			if content.multiplier != 0 || len(content.multipliers) > 0 {
 				result += ":" + truncateZero(fmt.Sprintf("%v", content.multiplier))
 			}
+			for _, multiplier := range content.multipliers {
+				result += ":" + truncateZero(fmt.Sprintf("%v", multiplier))
+			}
+			for _, argument := range content.arguments {
+				result += ":" + argument
+			}
+			result += ">"
+		case extraNetwork:
+			result += "<" + parser.networkName(content) + ":" + escapeFilename(content.filename)
+			for _, multiplier := range content.multipliers {
+				result += ":" + truncateZero(fmt.Sprintf("%v", multiplier))
+			}
+			for _, argument := range content.arguments {
+				result += ":" + argument
+			}
 			result += ">"
 		default:
-			result += content.name
+			result += escapeName(content.name)
 		}
 
-		lastPromptIsTag = content.kind == tag
+		lastPromptIsTag = isTag
 	}
 
 	return result
 }
 
 func (parser *PromptParser) toString(prompt *prompt) string {
+	switch parser.dialect {
+	case NovelAI:
+		return parser.novelAIContentsToString(prompt.contents)
+	case Compel:
+		return parser.compelContentsToString(prompt.contents)
+	case Midjourney:
+		return parser.midjourneyToString(prompt)
+	}
+
+	if len(prompt.contents) > 0 && prompt.contents[0].kind == composable {
+		subPrompts := make([]string, len(prompt.contents))
+		for i, content := range prompt.contents {
+			subPrompts[i] = parser.toString(content)
+			if content.weight != 1 {
+				subPrompts[i] += " :" + truncateZero(fmt.Sprintf("%v", content.weight))
+			}
+		}
+
+		return strings.TrimSpace(strings.Join(subPrompts, " AND "))
+	}
+
 	result := parser.contentsToString(prompt.contents)
 
 	regex := regexp.MustCompile(`( [<(\[])`)
 	result = regex.ReplaceAllString(result, ",$1")
 
-	regex = regexp.MustCompile(`([>)\]] )`)
-	result = regex.ReplaceAllStringFunc(result, func(entry string) string {
-		return entry[:1] + ", "
-	})
+	regex = regexp.MustCompile(`(^|[^\\])([>)\]]) `)
+	result = regex.ReplaceAllString(result, "$1$2, ")
+
+	// region keywords stay apart from the neighbour tags
+	regex = regexp.MustCompile(`,?(^| )(` + strings.Join(regionKeywords, "|") + `)(,|\b)`)
+	result = regex.ReplaceAllString(result, "$1$2")
 
 	return result
 }
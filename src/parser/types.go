@@ -1,27 +1,82 @@
 package parser
 
 type prompt struct {
-	kind       string
-	name       string
-	filename   string
-	multiplier float64
-	weight     float64
-	tokens     []string
-	contents   []*prompt
+	kind        string
+	name        string
+	prefix      string
+	alias       string
+	filename    string
+	multiplier  float64
+	multipliers []float64
+	arguments   []string
+	weight      float64
+	tokens      []string
+	contents    []*prompt
+	to          []*prompt
+	options     [][]*prompt
+	when        float64
+	diagnostics []*Diagnostic
 }
 
 type PromptTag struct {
 	Tag    string  `json:"tag"`
 	Weight float64 `json:"weight"`
+	Chunk  int     `json:"chunk"`
+}
+
+type PromptEmbedding struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
+}
+
+type PromptWildcard struct {
+	Name   string  `json:"name"`
+	Weight float64 `json:"weight"`
 }
 
 type PromptModel struct {
-	Filename   string  `json:"filename"`
-	Multiplier float64 `json:"multiplier"`
+	Filename    string            `json:"filename"`
+	Multiplier  float64           `json:"multiplier"`
+	TextEncoder *float64          `json:"textEncoder,omitempty"`
+	UNet        *float64          `json:"unet,omitempty"`
+	Args        map[string]string `json:"args,omitempty"`
+}
+
+type SubPrompt struct {
+	Weight float64       `json:"weight"`
+	Prompt *ParsedPrompt `json:"prompt"`
+}
+
+type PromptRegion struct {
+	Kind   string        `json:"kind"`
+	Row    int           `json:"row"`
+	Column int           `json:"column"`
+	Prompt *ParsedPrompt `json:"prompt"`
+}
+
+type PromptNetwork struct {
+	Kind        string    `json:"kind"`
+	Filename    string    `json:"filename"`
+	Multipliers []float64 `json:"multipliers,omitempty"`
+	Args        []string  `json:"args,omitempty"`
 }
 
 type ParsedPrompt struct {
-	Tags      []*PromptTag   `json:"tags"`
-	Loras     []*PromptModel `json:"loras"`
-	Hypernets []*PromptModel `json:"hypernets"`
+	Tags        []*PromptTag       `json:"tags"`
+	Loras       []*PromptModel     `json:"loras"`
+	Hypernets   []*PromptModel     `json:"hypernets"`
+	Embeddings  []*PromptEmbedding `json:"embeddings"`
+	Networks    []*PromptNetwork   `json:"networks"`
+	Wildcards   []*PromptWildcard  `json:"wildcards"`
+	SubPrompts  []*SubPrompt       `json:"subPrompts,omitempty"`
+	Breaks      int                `json:"breaks,omitempty"`
+	Parameters  map[string]string  `json:"parameters,omitempty"`
+	Regions     []*PromptRegion    `json:"regions,omitempty"`
+	Diagnostics []*Diagnostic      `json:"diagnostics,omitempty"`
+}
+
+type TimelineEntry struct {
+	FromStep int           `json:"fromStep"`
+	ToStep   int           `json:"toStep"`
+	Prompt   *ParsedPrompt `json:"prompt"`
 }
@@ -4,12 +4,11 @@ import "errors"
 
 type TokenReader struct {
 	index  int
-	tokens []string
+	tokens []*Token
 	length int
 }
 
-func NewTokenReader(input string) *TokenReader {
-	tokens := tokenizeInput(input)
+func newTokenReader(tokens []*Token) *TokenReader {
 	return &TokenReader{
 		index:  0,
 		tokens: tokens,
@@ -17,19 +16,39 @@ func NewTokenReader(input string) *TokenReader {
 	}
 }
 
+func NewTokenReader(input string) *TokenReader {
+	return newTokenReader(tokenizeInput(input))
+}
+
+func NewNovelAITokenReader(input string) *TokenReader {
+	return newTokenReader(tokenizeNovelAIInput(input))
+}
+
+func NewCompelTokenReader(input string) *TokenReader {
+	return newTokenReader(tokenizeCompelInput(input))
+}
+
 func (reader *TokenReader) GetToken() string {
 	if reader.index < reader.length {
-		return reader.tokens[reader.index]
+		return reader.tokens[reader.index].Value
 	}
 	return ""
 }
 
+// GetTokenPosition returns the current token with its offsets, nil after the last token.
+func (reader *TokenReader) GetTokenPosition() *Token {
+	if reader.index < reader.length {
+		return reader.tokens[reader.index]
+	}
+	return nil
+}
+
 func (reader *TokenReader) GetMultipleTokens(count int) ([]string, error) {
 	if reader.index+count <= reader.length {
 		values := make([]string, count)
 
 		for i := 0; i < count; i++ {
-			values[i] = reader.tokens[reader.index+i]
+			values[i] = reader.tokens[reader.index+i].Value
 		}
 		return values, nil
 	}
@@ -20,3 +20,18 @@ func TestTokenReader(t *testing.T) {
 	_, err = reader.GetMultipleTokens(10)
 	assert.EqualError(t, err, "count out of range")
 }
+
+func TestTokenReaderPosition(t *testing.T) {
+	reader := NewTokenReader("(猫:1.5)")
+
+	reader.NextToken()
+	assert.Equal(t, &Token{Value: "猫", Offset: 1, RuneOffset: 1}, reader.GetTokenPosition())
+
+	reader.NextToken()
+	reader.NextToken()
+	assert.Equal(t, &Token{Value: "1.5", Offset: 5, RuneOffset: 3}, reader.GetTokenPosition())
+
+	reader.NextToken()
+	reader.NextToken()
+	assert.Nil(t, reader.GetTokenPosition())
+}
@@ -1,54 +1,219 @@
 package reader
 
-import "strings"
+import (
+	"strings"
+	"unicode/utf8"
+)
 
-func addTokens(tokens *[]string, input *string, start *int, end *int) {
-	if *end <= len(*input) && *start < *end {
-		*tokens = append(*tokens, strings.Trim((*input)[*start:*end], " "))
+// Token is a part of the input with byte and rune offsets of its first character.
+type Token struct {
+	Value      string
+	Offset     int
+	RuneOffset int
+}
+
+type scanner struct {
+	input  string
+	tokens []*Token
+	// the current character
+	index     int
+	runeIndex int
+	// start of the pending token
+	start     int
+	runeStart int
+}
+
+func newScanner(input string) *scanner {
+	return &scanner{input: input, tokens: []*Token{}}
+}
+
+func (scanner *scanner) done() bool {
+	return scanner.index >= len(scanner.input)
+}
+
+func (scanner *scanner) char() rune {
+	char, _ := utf8.DecodeRuneInString(scanner.input[scanner.index:])
+	return char
+}
+
+func (scanner *scanner) next() {
+	if !scanner.done() {
+		_, size := utf8.DecodeRuneInString(scanner.input[scanner.index:])
+		scanner.index += size
+		scanner.runeIndex++
 	}
 }
 
-func tokenizeModel(tokens *[]string, input *string, start *int, end *int) {
-	for {
-		if *end >= len(*input) {
-			break
-		}
+// mark starts the pending token at the current character
+func (scanner *scanner) mark() {
+	scanner.start, scanner.runeStart = scanner.index, scanner.runeIndex
+}
+
+// pending returns text from the mark up to the current character without surrounding spaces
+func (scanner *scanner) pending() *Token {
+	value := scanner.input[scanner.start:scanner.index]
+	trimmed := strings.TrimLeft(value, " ")
+	skipped := len(value) - len(trimmed)
+
+	return &Token{
+		Value:      strings.TrimRight(trimmed, " "),
+		Offset:     scanner.start + skipped,
+		RuneOffset: scanner.runeStart + skipped,
+	}
+}
+
+func (scanner *scanner) addPending() {
+	if scanner.start < scanner.index {
+		scanner.tokens = append(scanner.tokens, scanner.pending())
+	}
+}
+
+// addChar adds value starting at the current character as a separate token and marks the next one
+func (scanner *scanner) addChar(value string) {
+	scanner.tokens = append(scanner.tokens, &Token{Value: value, Offset: scanner.index, RuneOffset: scanner.runeIndex})
+	for range value {
+		scanner.next()
+	}
+	scanner.mark()
+}
+
+func (scanner *scanner) skipSpace() {
+	scanner.addPending()
+	scanner.next()
+	scanner.mark()
+}
+
+// skipEscaped keeps the backslash and the escaped character in the token
+func (scanner *scanner) skipEscaped() {
+	scanner.next()
+	scanner.next()
+}
 
-		char := (*input)[*end]
+func (scanner *scanner) scanModel() {
+	for !scanner.done() {
+		char := scanner.char()
 		switch char {
+		case '\\':
+			scanner.skipEscaped()
 		case '<', ':', '>':
-			addTokens(tokens, input, start, end)
-			*tokens = append(*tokens, string(char))
-			*start = *end + 1
+			scanner.addPending()
+			scanner.addChar(string(char))
+			if char == '>' {
+				return
+			}
+		default:
+			scanner.next()
 		}
+	}
+
+	// RECOVER: text of an unclosed model is dropped
+	scanner.mark()
+}
 
-		if char == '>' {
-			break
+func tokenizeInput(input string) []*Token {
+	scanner := newScanner(input)
+	for !scanner.done() {
+		char := scanner.char()
+		switch char {
+		case '\\':
+			scanner.skipEscaped()
+		case '(', ')', '[', ']', ':', ',', '|':
+			scanner.addPending()
+			scanner.addChar(string(char))
+		case '<':
+			scanner.scanModel()
+		case ' ':
+			scanner.skipSpace()
+		default:
+			scanner.next()
 		}
+	}
+
+	scanner.addPending()
 
-		*end++
+	return scanner.tokens
+}
+
+func tokenizeNovelAIInput(input string) []*Token {
+	scanner := newScanner(input)
+	for !scanner.done() {
+		char := scanner.char()
+		switch char {
+		case '\\':
+			scanner.skipEscaped()
+		case '{', '}', '[', ']', ',', '|':
+			scanner.addPending()
+			scanner.addChar(string(char))
+		case ':':
+			// a single colon is a part of a tag, :: opens and closes numeric emphasis
+			if strings.HasPrefix(scanner.input[scanner.index:], "::") {
+				scanner.addPending()
+				scanner.addChar("::")
+			} else {
+				scanner.next()
+			}
+		case ' ':
+			scanner.skipSpace()
+		default:
+			scanner.next()
+		}
+	}
+
+	scanner.addPending()
+
+	return scanner.tokens
+}
+
+func (scanner *scanner) addCompelPending() {
+	token := scanner.pending()
+	if scanner.start >= scanner.index || token.Value == "" {
+		return
+	}
+
+	// word++ and word-- are split into the word and its emphasis
+	base := strings.TrimRight(token.Value, "+-")
+	if base == "" || base == token.Value {
+		scanner.tokens = append(scanner.tokens, token)
+		return
 	}
+
+	scanner.tokens = append(scanner.tokens,
+		&Token{Value: base, Offset: token.Offset, RuneOffset: token.RuneOffset},
+		&Token{Value: token.Value[len(base):], Offset: token.Offset + len(base), RuneOffset: token.RuneOffset + utf8.RuneCountInString(base)},
+	)
 }
 
-func tokenizeInput(input string) (tokens []string) {
-	var current int
-	var index int
-	for index = 0; index < len(input); index++ {
-		char := input[index]
+func tokenizeCompelInput(input string) []*Token {
+	scanner := newScanner(input)
+	for !scanner.done() {
+		char := scanner.char()
 		switch char {
-		case '(', ')', '[', ']', ':', ',', '|':
-			addTokens(&tokens, &input, &current, &index)
-			tokens = append(tokens, string(char))
-			current = index + 1
-		case '<':
-			tokenizeModel(&tokens, &input, &current, &index)
+		case '\\':
+			scanner.skipEscaped()
+		case '(', ')', ',':
+			scanner.addCompelPending()
+			scanner.addChar(string(char))
+		case '"':
+			scanner.addCompelPending()
+			scanner.mark()
+			for scanner.next(); !scanner.done() && scanner.char() != '"'; scanner.next() {
+				if scanner.char() == '\\' {
+					scanner.next()
+				}
+			}
+			scanner.next()
+			scanner.tokens = append(scanner.tokens, &Token{Value: input[scanner.start:scanner.index], Offset: scanner.start, RuneOffset: scanner.runeStart})
+			scanner.mark()
 		case ' ':
-			addTokens(&tokens, &input, &current, &index)
-			current = index + 1
+			scanner.addCompelPending()
+			scanner.next()
+			scanner.mark()
+		default:
+			scanner.next()
 		}
 	}
 
-	addTokens(&tokens, &input, &current, &index)
+	scanner.addCompelPending()
 
-	return tokens
+	return scanner.tokens
 }
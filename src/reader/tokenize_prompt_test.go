@@ -3,10 +3,20 @@ package reader
 import (
 	"reflect"
 	"testing"
+	"unicode/utf8"
 
 	"github.com/stretchr/testify/assert"
 )
 
+func tokenValues(tokens []*Token) []string {
+	values := make([]string, len(tokens))
+	for i, token := range tokens {
+		values[i] = token.Value
+	}
+
+	return values
+}
+
 func TestTokenizePrompt(t *testing.T) {
 	tests := []struct {
 		input  string
@@ -44,12 +54,175 @@ func TestTokenizePrompt(t *testing.T) {
 			"<lora:file name:1.5>",
 			[]string{"<", "lora", ":", "file name", ":", "1.5", ">"},
 		},
+		{
+			"kafka \\(honkai\\), \\[abc\\:1\\]",
+			[]string{"kafka", "\\(honkai\\)", ",", "\\[abc\\:1\\]"},
+		},
+		{
+			"abc\\\\(xyz)",
+			[]string{"abc\\\\", "(", "xyz", ")"},
+		},
+		{
+			"<lora:file\\:name>",
+			[]string{"<", "lora", ":", "file\\:name", ">"},
+		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.input, func(t *testing.T) {
-			result := tokenizeInput(test.input)
+			result := tokenValues(tokenizeInput(test.input))
 			assert.True(t, reflect.DeepEqual(result, test.result))
 		})
 	}
 }
+
+func TestTokenizeNovelAIPrompt(t *testing.T) {
+	tests := []struct {
+		input  string
+		result []string
+	}{
+		{
+			"{abc}, [xyz]",
+			[]string{"{", "abc", "}", ",", "[", "xyz", "]"},
+		},
+		{
+			"artist (style), ratio 16:9",
+			[]string{"artist", "(style)", ",", "ratio", "16:9"},
+		},
+		{
+			"1.5::abc xyz::, -1::mno ::",
+			[]string{"1.5", "::", "abc", "xyz", "::", ",", "-1", "::", "mno", "::"},
+		},
+		{
+			"\\{abc\\}",
+			[]string{"\\{abc\\}"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result := tokenValues(tokenizeNovelAIInput(test.input))
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestTokenizeCompelPrompt(t *testing.T) {
+	tests := []struct {
+		input  string
+		result []string
+	}{
+		{
+			"red hair++, t-shirt-",
+			[]string{"red", "hair", "++", ",", "t-shirt", "-"},
+		},
+		{
+			"(red hair)1.3",
+			[]string{"(", "red", "hair", ")", "1.3"},
+		},
+		{
+			`("a cat", "a (dog)").blend(0.7, 0.3)`,
+			[]string{"(", `"a cat"`, ",", `"a (dog)"`, ")", ".blend", "(", "0.7", ",", "0.3", ")"},
+		},
+		{
+			`"unclosed`,
+			[]string{`"unclosed`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result := tokenValues(tokenizeCompelInput(test.input))
+			assert.Equal(t, test.result, result)
+		})
+	}
+}
+
+func TestTokenizeOffsets(t *testing.T) {
+	tests := []struct {
+		name     string
+		tokenize func(string) []*Token
+		input    string
+		result   []*Token
+	}{
+		{
+			"multibyte",
+			tokenizeInput,
+			"(猫:1.2), 🐱 cat",
+			[]*Token{
+				{Value: "(", Offset: 0, RuneOffset: 0},
+				{Value: "猫", Offset: 1, RuneOffset: 1},
+				{Value: ":", Offset: 4, RuneOffset: 2},
+				{Value: "1.2", Offset: 5, RuneOffset: 3},
+				{Value: ")", Offset: 8, RuneOffset: 6},
+				{Value: ",", Offset: 9, RuneOffset: 7},
+				{Value: "🐱", Offset: 11, RuneOffset: 9},
+				{Value: "cat", Offset: 16, RuneOffset: 11},
+			},
+		},
+		{
+			"combining characters",
+			tokenizeInput,
+			"cafe\u0301 <lora:nai\u0308ve:1>",
+			[]*Token{
+				{Value: "cafe\u0301", Offset: 0, RuneOffset: 0},
+				{Value: "<", Offset: 7, RuneOffset: 6},
+				{Value: "lora", Offset: 8, RuneOffset: 7},
+				{Value: ":", Offset: 12, RuneOffset: 11},
+				{Value: "nai\u0308ve", Offset: 13, RuneOffset: 12},
+				{Value: ":", Offset: 20, RuneOffset: 18},
+				{Value: "1", Offset: 21, RuneOffset: 19},
+				{Value: ">", Offset: 22, RuneOffset: 20},
+			},
+		},
+		{
+			"escaped multibyte",
+			tokenizeInput,
+			"\\（猫\\） [犬]",
+			[]*Token{
+				{Value: "\\（猫\\）", Offset: 0, RuneOffset: 0},
+				{Value: "[", Offset: 12, RuneOffset: 6},
+				{Value: "犬", Offset: 13, RuneOffset: 7},
+				{Value: "]", Offset: 16, RuneOffset: 8},
+			},
+		},
+		{
+			"novelai",
+			tokenizeNovelAIInput,
+			"{ネコ}, 1.5::犬::",
+			[]*Token{
+				{Value: "{", Offset: 0, RuneOffset: 0},
+				{Value: "ネコ", Offset: 1, RuneOffset: 1},
+				{Value: "}", Offset: 7, RuneOffset: 3},
+				{Value: ",", Offset: 8, RuneOffset: 4},
+				{Value: "1.5", Offset: 10, RuneOffset: 6},
+				{Value: "::", Offset: 13, RuneOffset: 9},
+				{Value: "犬", Offset: 15, RuneOffset: 11},
+				{Value: "::", Offset: 18, RuneOffset: 12},
+			},
+		},
+		{
+			"compel",
+			tokenizeCompelInput,
+			`ネコ++ "犬 dog"-`,
+			[]*Token{
+				{Value: "ネコ", Offset: 0, RuneOffset: 0},
+				{Value: "++", Offset: 6, RuneOffset: 2},
+				{Value: `"犬 dog"`, Offset: 9, RuneOffset: 5},
+				{Value: "-", Offset: 18, RuneOffset: 12},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result := test.tokenize(test.input)
+			assert.Equal(t, test.result, result)
+
+			for _, token := range result {
+				assert.Equal(t, token.Value, test.input[token.Offset:token.Offset+len(token.Value)])
+				assert.Equal(t, token.Value, string([]rune(test.input)[token.RuneOffset:token.RuneOffset+utf8.RuneCountInString(token.Value)]))
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package variant
+
+import (
+	"math/rand"
+	"strings"
+)
+
+type Expander struct {
+	random *rand.Rand
+}
+
+func NewExpander(seed int64) *Expander {
+	return &Expander{random: rand.New(rand.NewSource(seed))}
+}
+
+func (expander *Expander) Expand(input string) (string, error) {
+	parts, err := parseTemplate(input)
+	if err != nil {
+		return "", err
+	}
+
+	return expander.render(parts), nil
+}
+
+func (expander *Expander) render(parts []*part) string {
+	result := strings.Builder{}
+	for _, part := range parts {
+		if part.variant == nil {
+			result.WriteString(part.text)
+			continue
+		}
+
+		result.WriteString(expander.renderVariant(part.variant))
+	}
+
+	return result.String()
+}
+
+func (expander *Expander) renderVariant(variant *variant) string {
+	count := variant.min + expander.random.Intn(variant.max-variant.min+1)
+
+	options := append([]*option{}, variant.options...)
+	values := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		total := 0.0
+		for _, option := range options {
+			total += option.weight
+		}
+
+		index, target := len(options)-1, expander.random.Float64()*total
+		for j, option := range options {
+			if target < option.weight {
+				index = j
+				break
+			}
+			target -= option.weight
+		}
+
+		values = append(values, expander.render(options[index].parts))
+		options = append(options[:index], options[index+1:]...)
+	}
+
+	return strings.Join(values, variant.joiner)
+}
+
+// Combinations returns every prompt the input can produce, at most limit of them (0 means no limit).
+func Combinations(input string, limit int) ([]string, error) {
+	parts, err := parseTemplate(input)
+	if err != nil {
+		return nil, err
+	}
+
+	return combineParts(parts, limit), nil
+}
+
+func combineParts(parts []*part, limit int) []string {
+	results := []string{""}
+	for _, part := range parts {
+		if part.variant == nil {
+			for i := range results {
+				results[i] += part.text
+			}
+			continue
+		}
+
+		results = product(results, combineVariant(part.variant, limit), "", limit)
+	}
+
+	return results
+}
+
+func combineVariant(variant *variant, limit int) []string {
+	results := []string{}
+	for count := variant.min; count <= variant.max; count++ {
+		choose(len(variant.options), count, func(indexes []int) bool {
+			values := []string{""}
+			for i, index := range indexes {
+				joiner := variant.joiner
+				if i == 0 {
+					joiner = ""
+				}
+
+				values = product(values, combineParts(variant.options[index].parts, limit), joiner, limit)
+			}
+
+			results = append(results, values...)
+			return limit <= 0 || len(results) < limit
+		})
+
+		if limit > 0 && len(results) >= limit {
+			return results[:limit]
+		}
+	}
+
+	return results
+}
+
+func product(prefixes []string, suffixes []string, joiner string, limit int) []string {
+	results := []string{}
+	for _, prefix := range prefixes {
+		for _, suffix := range suffixes {
+			results = append(results, prefix+joiner+suffix)
+			if limit > 0 && len(results) >= limit {
+				return results
+			}
+		}
+	}
+
+	return results
+}
+
+// choose visits k of n indexes in order until visit returns false, combinations are
+// generated one by one, multi-picks of many options are too large to build up front
+func choose(n int, k int, visit func(indexes []int) bool) {
+	var walk func(start int, current []int) bool
+	walk = func(start int, current []int) bool {
+		if len(current) == k {
+			return visit(current)
+		}
+
+		for i := start; i < n; i++ {
+			if !walk(i+1, append(current, i)) {
+				return false
+			}
+		}
+
+		return true
+	}
+	walk(0, []int{})
+}
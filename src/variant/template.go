@@ -0,0 +1,151 @@
+package variant
+
+import (
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const defaultJoiner = ", "
+
+var headerRegex = regexp.MustCompile(`^(\d*)(?:(-)(\d*))?\$\$(?:([^|{}]*?)\$\$)?`)
+var weightRegex = regexp.MustCompile(`^\s*(\d+(?:\.\d+)?)::`)
+
+type part struct {
+	text    string
+	variant *variant
+}
+
+type option struct {
+	weight float64
+	parts  []*part
+}
+
+type variant struct {
+	options []*option
+	min     int
+	max     int
+	joiner  string
+}
+
+type templateParser struct {
+	input string
+	index int
+}
+
+func parseTemplate(input string) ([]*part, error) {
+	parser := &templateParser{input: input}
+	return parser.parseParts(false)
+}
+
+func (parser *templateParser) parseParts(inVariant bool) ([]*part, error) {
+	parts := []*part{}
+	text := strings.Builder{}
+	flush := func() {
+		if text.Len() > 0 {
+			parts = append(parts, &part{text: text.String()})
+			text.Reset()
+		}
+	}
+
+	depth := 0
+	for parser.index < len(parser.input) {
+		char, size := utf8.DecodeRuneInString(parser.input[parser.index:])
+		switch {
+		case char == '\\' && parser.index+size < len(parser.input):
+			// escaped characters are kept for the prompt parser
+			_, next := utf8.DecodeRuneInString(parser.input[parser.index+size:])
+			text.WriteString(parser.input[parser.index : parser.index+size+next])
+			parser.index += size + next
+			continue
+		case char == '{':
+			flush()
+			parser.index += size
+			variant, err := parser.parseVariant()
+			if err != nil {
+				return nil, err
+			}
+
+			parts = append(parts, &part{variant: variant})
+			continue
+		case char == '(' || char == '[':
+			depth++
+		case (char == ')' || char == ']') && depth > 0:
+			depth--
+		case inVariant && depth == 0 && (char == '|' || char == '}'):
+			flush()
+			return parts, nil
+		}
+
+		text.WriteRune(char)
+		parser.index += size
+	}
+
+	if inVariant {
+		return nil, errors.New("} expected")
+	}
+
+	flush()
+
+	return parts, nil
+}
+
+func (parser *templateParser) parseVariant() (*variant, error) {
+	variant := &variant{min: 1, max: 1, joiner: defaultJoiner}
+
+	if matches := headerRegex.FindStringSubmatch(parser.input[parser.index:]); len(matches) > 0 {
+		parser.index += len(matches[0])
+
+		lower := 1
+		if matches[1] != "" {
+			lower, _ = strconv.Atoi(matches[1])
+		}
+
+		upper := lower
+		if matches[2] == "-" {
+			// {2-$$a|b|c} picks at least 2, {-2$$a|b|c} picks up to 2
+			upper = -1
+			if matches[3] != "" {
+				upper, _ = strconv.Atoi(matches[3])
+			}
+		}
+
+		variant.min, variant.max = lower, upper
+		if matches[4] != "" {
+			variant.joiner = matches[4]
+		}
+	}
+
+	for {
+		option := &option{weight: 1}
+		if matches := weightRegex.FindStringSubmatch(parser.input[parser.index:]); len(matches) > 0 {
+			parser.index += len(matches[0])
+			option.weight, _ = strconv.ParseFloat(matches[1], 64)
+		}
+
+		parts, err := parser.parseParts(true)
+		if err != nil {
+			return nil, err
+		}
+
+		option.parts = parts
+		variant.options = append(variant.options, option)
+
+		char := parser.input[parser.index]
+		parser.index++
+		if char == '}' {
+			break
+		}
+	}
+
+	if variant.max < 0 || variant.max > len(variant.options) {
+		variant.max = len(variant.options)
+	}
+	if variant.min > variant.max {
+		variant.min = variant.max
+	}
+
+	return variant, nil
+}
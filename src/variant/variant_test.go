@@ -0,0 +1,74 @@
+package variant
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCombinations(t *testing.T) {
+	tests := []struct {
+		input  string
+		limit  int
+		result []string
+	}{
+		{"abc", 0, []string{"abc"}},
+		{"{abc|xyz}, mno", 0, []string{"abc, mno", "xyz, mno"}},
+		{"{2::abc|1::xyz}", 0, []string{"abc", "xyz"}},
+		{"{2$$abc|xyz|mno}", 0, []string{"abc, xyz", "abc, mno", "xyz, mno"}},
+		{"{1-2$$ and $$abc|xyz}", 0, []string{"abc", "xyz", "abc and xyz"}},
+		{"{abc|{x|y}z}", 0, []string{"abc", "xz", "yz"}},
+		{"{abc|[x|y]}", 0, []string{"abc", "[x|y]"}},
+		{"{a|b} {c|d}", 3, []string{"a c", "a d", "b c"}},
+		{"\\{abc|xyz\\}", 0, []string{"\\{abc|xyz\\}"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := Combinations(test.input, test.limit)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+
+	_, err := Combinations("{abc|xyz", 0)
+	assert.EqualError(t, err, "} expected")
+}
+
+func TestCombinationsLargeMultiPick(t *testing.T) {
+	letters := strings.Split("abcdefghijklmnopqrstuvwxyz", "")
+	result, err := Combinations("{13$$"+strings.Join(letters, "|")+"}", 2)
+	assert.Equal(t, nil, err)
+	assert.Equal(t, []string{
+		strings.Join(letters[:13], ", "),
+		strings.Join(append(append([]string{}, letters[:12]...), "n"), ", "),
+	}, result)
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		input   string
+		results []string
+	}{
+		{"{abc|xyz}, mno", []string{"abc, mno", "xyz, mno"}},
+		{"{1::abc|0::xyz}", []string{"abc"}},
+		{"{2$$+$$abc|xyz}", []string{"abc+xyz", "xyz+abc"}},
+	}
+
+	expander := NewExpander(1)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			for i := 0; i < 10; i++ {
+				result, err := expander.Expand(test.input)
+				assert.Equal(t, nil, err)
+				assert.Contains(t, test.results, result)
+			}
+		})
+	}
+
+	first, _ := NewExpander(42).Expand("{a|b|c|d|e}, {a|b|c|d|e}, {a|b|c|d|e}")
+	second, _ := NewExpander(42).Expand("{a|b|c|d|e}, {a|b|c|d|e}, {a|b|c|d|e}")
+	assert.Equal(t, first, second)
+}
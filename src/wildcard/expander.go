@@ -0,0 +1,149 @@
+package wildcard
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"path"
+	"regexp"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var wildcardRegex = regexp.MustCompile(`__(\S+?)__`)
+
+type Expander struct {
+	fsys        fs.FS
+	random      *rand.Rand
+	collections map[string][]string
+}
+
+func NewExpander(fsys fs.FS, seed int64) *Expander {
+	return &Expander{
+		fsys:   fsys,
+		random: rand.New(rand.NewSource(seed)),
+	}
+}
+
+func (expander *Expander) Expand(input string) (string, error) {
+	return expander.expand(input, []string{})
+}
+
+func (expander *Expander) expand(input string, stack []string) (string, error) {
+	var expandErr error
+	result := wildcardRegex.ReplaceAllStringFunc(input, func(match string) string {
+		if expandErr != nil {
+			return match
+		}
+
+		name := wildcardRegex.FindStringSubmatch(match)[1]
+		if slices.Contains(stack, name) {
+			expandErr = fmt.Errorf("wildcard cycle: %s -> %s", strings.Join(stack, " -> "), name)
+			return match
+		}
+
+		values, err := expander.values(name)
+		if err != nil {
+			expandErr = err
+			return match
+		}
+
+		value := values[expander.random.Intn(len(values))]
+		expanded, err := expander.expand(value, append(slices.Clone(stack), name))
+		if err != nil {
+			expandErr = err
+			return match
+		}
+
+		return expanded
+	})
+
+	return result, expandErr
+}
+
+func (expander *Expander) values(name string) ([]string, error) {
+	data, err := fs.ReadFile(expander.fsys, name+".txt")
+	if err == nil {
+		values := readLines(data)
+		if len(values) == 0 {
+			return nil, fmt.Errorf("wildcard %q is empty", name)
+		}
+
+		return values, nil
+	}
+
+	if expander.collections == nil {
+		if err := expander.loadCollections(); err != nil {
+			return nil, err
+		}
+	}
+
+	if values, ok := expander.collections[name]; ok && len(values) > 0 {
+		return values, nil
+	}
+
+	return nil, fmt.Errorf("wildcard %q not found", name)
+}
+
+func (expander *Expander) loadCollections() error {
+	expander.collections = map[string][]string{}
+
+	return fs.WalkDir(expander.fsys, ".", func(filename string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		extension := path.Ext(filename)
+		if entry.IsDir() || (extension != ".yaml" && extension != ".yml") {
+			return nil
+		}
+
+		data, err := fs.ReadFile(expander.fsys, filename)
+		if err != nil {
+			return err
+		}
+
+		var collection map[string]any
+		if err := yaml.Unmarshal(data, &collection); err != nil {
+			return fmt.Errorf("%s: %v", filename, err)
+		}
+
+		flattenCollection(expander.collections, "", collection)
+
+		return nil
+	})
+}
+
+func flattenCollection(collections map[string][]string, prefix string, collection map[string]any) {
+	for key, value := range collection {
+		name := path.Join(prefix, key)
+		switch value := value.(type) {
+		case map[string]any:
+			flattenCollection(collections, name, value)
+		case []any:
+			for _, item := range value {
+				collections[name] = append(collections[name], fmt.Sprintf("%v", item))
+			}
+		case string:
+			collections[name] = append(collections[name], value)
+		}
+	}
+}
+
+func readLines(data []byte) (lines []string) {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lines = append(lines, line)
+	}
+
+	return lines
+}
@@ -0,0 +1,57 @@
+package wildcard
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpand(t *testing.T) {
+	fsys := fstest.MapFS{
+		"color.txt":        {Data: []byte("# colors\nred\n\n")},
+		"clothes/tops.txt": {Data: []byte("__color__ shirt\n")},
+		"cycle.txt":        {Data: []byte("__loop__\n")},
+		"loop.txt":         {Data: []byte("__cycle__\n")},
+		"styles.yaml":      {Data: []byte("styles:\n  art:\n    - oil painting\n")},
+	}
+
+	tests := []struct {
+		input  string
+		result string
+	}{
+		{"abc, __color__ hair", "abc, red hair"},
+		{"(__clothes/tops__:1.2)", "(red shirt:1.2)"},
+		{"__styles/art__", "oil painting"},
+		{"abc", "abc"},
+	}
+
+	expander := NewExpander(fsys, 1)
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			result, err := expander.Expand(test.input)
+			assert.Equal(t, nil, err)
+			assert.Equal(t, test.result, result)
+		})
+	}
+
+	_, err := expander.Expand("__cycle__")
+	assert.EqualError(t, err, "wildcard cycle: cycle -> loop -> cycle")
+
+	_, err = expander.Expand("__missing__")
+	assert.EqualError(t, err, `wildcard "missing" not found`)
+}
+
+func TestExpandSeed(t *testing.T) {
+	fsys := fstest.MapFS{
+		"animal.txt": {Data: []byte("cat\ndog\nhorse\nfox\nowl\n")},
+	}
+
+	first, err := NewExpander(fsys, 42).Expand("__animal__, __animal__, __animal__")
+	assert.Equal(t, nil, err)
+
+	second, err := NewExpander(fsys, 42).Expand("__animal__, __animal__, __animal__")
+	assert.Equal(t, nil, err)
+	assert.Equal(t, first, second)
+}